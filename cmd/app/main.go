@@ -1,13 +1,51 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"trace/package/agent"
+	"trace/package/analysis"
+	"trace/package/executor"
 	"trace/package/logger"
 	"trace/package/parser"
 	"trace/package/scheduler"
+	"trace/package/validator"
 )
 
 func main() {
+	mock := flag.Bool("mock", true, "simulate agent calls instead of sending HTTP requests")
+	timeout := flag.Duration("timeout", executor.DefaultTimeout, "timeout for each HTTP call to an agent")
+	agentsFile := flag.String("agents", "", "JSON file of enrolled agents (defaults to the built-in mock agents)")
+	healthInterval := flag.Duration("health", 0, "probe agent health at this interval (0 disables health checks)")
+	printGraph := flag.Bool("graph", false, "print the dependencies inferred for each AUTO block instead of running the script")
+	strict := flag.Bool("strict", false, "treat races between concurrent tasks as errors rather than warnings")
+	parallel := flag.Int("parallel", 0, "most tasks calling agents at once across the whole script (0 for no limit)")
+	agentLimits := flag.String("agent-limits", "", "most tasks calling each agent at once, as Name=N pairs separated by commas")
+	flag.Parse()
+
+	limits, err := parseAgentLimits(*agentLimits)
+	if err != nil {
+		fmt.Println("Error in -agent-limits:", err)
+		return
+	}
+
+	// Load the agent registry
+	var registry agent.AgentRegistry = agent.NewMockRegistry()
+	if *agentsFile != "" {
+		fileRegistry, err := agent.NewFileRegistry(*agentsFile)
+		if err != nil {
+			fmt.Println("Error loading agents:", err)
+			return
+		}
+		registry = fileRegistry
+	}
+
 	input := `
 START
     DATA origin TYPE String VALUE "Chicago" ;
@@ -87,19 +125,92 @@ END
 		return
 	}
 
+	// Catch undeclared data, missing permissions, unknown agents and races before anything runs
+	issues := validator.ValidateWithOptions(parentRequest, registry, validator.Options{Strict: *strict})
+	if validator.HasErrors(issues) {
+		fmt.Println("Validation errors:")
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+		return
+	}
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+
+	// Show how AUTO blocks will be scheduled, for review
+	if *printGraph {
+		parser.InspectAll(parentRequest.Statements, func(stmt parser.Statement) bool {
+			if autoBlock, ok := stmt.(*parser.AutoBlock); ok {
+				fmt.Printf("AUTO at %s:\n%s", autoBlock.Span.Start, analysis.InferGraph(autoBlock))
+			}
+			return true
+		})
+		return
+	}
+
 	// Create a logger
 	lg := logger.NewLogger()
 
-	// Run the parent request (the script)
+	// Mark unreachable agents down before running so the executor can route around them
+	if *healthInterval > 0 {
+		monitor := agent.NewHealthMonitor(registry, *healthInterval)
+		monitor.CheckAll()
+		monitor.Start()
+		defer monitor.Stop()
+	}
+
+	// The mock agents point at placeholder endpoints, so simulate calls unless told otherwise
+	e := executor.NewMockExecutor(registry)
+	if !*mock {
+		e = executor.NewExecutor(registry, *timeout)
+	}
+
+	// Run the parent request (the script); Ctrl-C cancels the tasks in flight
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 	fmt.Println("Starting Execution:")
-	success := scheduler.RunParentRequest(parentRequest, lg)
+	pool := scheduler.NewWorkerPool(*parallel, limits)
+	result := scheduler.RunParentRequestWithOptions(ctx, parentRequest, e, lg, scheduler.Options{Pool: pool})
 
 	// Print logs
 	lg.PrintAllLogs()
 
-	if !success {
-		fmt.Println("Execution failed.")
+	// Summarize each task and the data the run ended with
+	fmt.Println("Tasks:")
+	for _, t := range result.Tasks {
+		fmt.Println(t)
+	}
+	fmt.Println("Final data:")
+	names := make([]string, 0, len(result.Data))
+	for name := range result.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s = %q\n", name, result.Data[name])
+	}
+
+	if !result.Succeeded() {
+		fmt.Println("Execution failed:", result.Err)
 	} else {
 		fmt.Println("Execution succeeded.")
 	}
 }
+
+// parseAgentLimits parses per-agent concurrency limits such as "RoomBooker=1,FlightGetter=2".
+func parseAgentLimits(s string) (map[string]int, error) {
+	limits := make(map[string]int)
+	if s == "" {
+		return limits, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		limit, err := strconv.Atoi(value)
+		if !found || name == "" || err != nil || limit < 1 {
+			return nil, fmt.Errorf("expected Name=N with N a positive whole number, got %q", pair)
+		}
+		limits[name] = limit
+	}
+	return limits, nil
+}
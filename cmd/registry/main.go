@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+	"trace/package/agent"
+	"trace/package/enrollment"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to serve the enrollment API on")
+	agentsFile := flag.String("agents", "agents.json", "JSON file the enrolled agents are stored in")
+	healthInterval := flag.Duration("health", 30*time.Second, "probe agent health at this interval (0 disables health checks)")
+	flag.Parse()
+
+	registry, err := agent.NewFileRegistry(*agentsFile)
+	if err != nil {
+		fmt.Println("Error loading agents:", err)
+		return
+	}
+
+	monitor := agent.NewHealthMonitor(registry, *healthInterval)
+	if *healthInterval > 0 {
+		monitor.Start()
+		defer monitor.Stop()
+	}
+
+	fmt.Printf("Serving agent enrollment on %s (agents stored in %s)\n", *addr, *agentsFile)
+	if err := http.ListenAndServe(*addr, enrollment.NewServer(registry, monitor)); err != nil {
+		fmt.Println("Server error:", err)
+	}
+}
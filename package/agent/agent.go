@@ -2,19 +2,30 @@ package agent
 
 import (
 	"sync"
-	"fmt"
+	"time"
+)
+
+// Transport kinds an agent can be reached through. The meaning of Endpoint depends on the kind:
+// a URL for HTTP, a command line for subprocess agents and a registered function name for in-process agents.
+const (
+	TransportHTTP       = "http"
+	TransportSubprocess = "subprocess"
+	TransportInProcess  = "inprocess"
 )
 
 // BaseAgent provides a base implementation of the Agent interface.
 type BaseAgent struct {
-	ID           string
-	Name         string
-	AgentType    string
-	Endpoint     string
-	JsonBody     map[string]interface{}
-	Reputation   float32
-	Capabilities []string
-	mu           sync.Mutex
+	ID            string                 `json:"id"`
+	Name          string                 `json:"name"`
+	AgentType     string                 `json:"agentType"`
+	Endpoint      string                 `json:"endpoint"`
+	Transport     string                 `json:"transport,omitempty"` // One of the Transport* kinds; empty means HTTP
+	JsonBody      map[string]interface{} `json:"jsonBody"`
+	Reputation    float32                `json:"reputation"`
+	Capabilities  []string               `json:"capabilities"`
+	availability  Availability
+	lastHeartbeat time.Time
+	mu            sync.Mutex
 }
 
 // NewBaseAgent creates a new BaseAgent instance.
@@ -50,6 +61,14 @@ func (a *BaseAgent) GetEndpoint() string {
 	return a.Endpoint
 }
 
+// GetTransport returns the kind of transport used to reach the agent, defaulting to HTTP.
+func (a *BaseAgent) GetTransport() string {
+	if a.Transport == "" {
+		return TransportHTTP
+	}
+	return a.Transport
+}
+
 // GetReputation returns the agent's reputation.
 func (a *BaseAgent) GetReputation() float32 {
 	return a.Reputation
@@ -67,6 +86,55 @@ func (a *BaseAgent) GetJsonBody() map[string]interface{} {
 	return a.JsonBody
 }
 
+// GetAvailability returns the agent's last known availability.
+func (a *BaseAgent) GetAvailability() Availability {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.availability
+}
+
+// SetAvailability records the agent's availability.
+func (a *BaseAgent) SetAvailability(availability Availability) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.availability = availability
+}
+
+// IsAvailable reports whether tasks may be dispatched to the agent. Agents that have never been checked are given the benefit of the doubt.
+func (a *BaseAgent) IsAvailable() bool {
+	return a.GetAvailability() != Unavailable
+}
+
+// RecordHeartbeat marks the agent available as of the given time.
+func (a *BaseAgent) RecordHeartbeat(at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastHeartbeat = at
+	a.availability = Available
+}
+
+// LastHeartbeat returns when the agent last sent a heartbeat, or the zero time if it never has.
+func (a *BaseAgent) LastHeartbeat() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.lastHeartbeat
+}
+
+// inheritHealth copies the availability and last heartbeat of the agent this one replaces.
+func (a *BaseAgent) inheritHealth(previous *BaseAgent) {
+	if previous == a {
+		return
+	}
+	previous.mu.Lock()
+	availability, lastHeartbeat := previous.availability, previous.lastHeartbeat
+	previous.mu.Unlock()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.availability = availability
+	a.lastHeartbeat = lastHeartbeat
+}
+
 // GetMockAgents returns an array of mock base agents.
 func GetMockAgents() []*BaseAgent {
 	mockAgents := []*BaseAgent{
@@ -116,31 +184,3 @@ func GetMockAgents() []*BaseAgent {
 	return mockAgents
 }
 
-
-// SimulateLoadAgent returns predefined agents based on the given identifier type and value.
-func SimulateLoadAgent(identifierType string, identifierValue string) *BaseAgent {
-	mockAgents := GetMockAgents()
-
-	for _, agent := range mockAgents {
-		switch identifierType {
-		case "ID":
-			if agent.ID == identifierValue {
-				return agent
-			}
-		case "Name":
-			if agent.Name == identifierValue {
-				return agent
-			}
-		case "AgentType":
-			if agent.AgentType == identifierValue {
-				return agent
-			}
-		default:
-			fmt.Printf("Unknown identifier type: %s\n", identifierType)
-			return nil
-		}
-	}
-
-	fmt.Printf("Agent not found for %s: %s\n", identifierType, identifierValue)
-	return nil
-}
\ No newline at end of file
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Availability represents an agent's last known liveness.
+type Availability int
+
+const (
+	Unknown Availability = iota
+	Available
+	Unavailable
+)
+
+// String returns a human-readable availability.
+func (a Availability) String() string {
+	switch a {
+	case Unknown:
+		return "Unknown"
+	case Available:
+		return "Available"
+	case Unavailable:
+		return "Unavailable"
+	default:
+		return "Invalid"
+	}
+}
+
+// ErrProbeUnsupported is returned by a ProbeFunc that cannot check the given agent; its availability is left unchanged.
+var ErrProbeUnsupported = errors.New("probe not supported for agent")
+
+// ProbeFunc checks whether an agent is reachable.
+type ProbeFunc func(a *BaseAgent) error
+
+// HTTPProbe returns a ProbeFunc that sends a GET request to an HTTP agent's endpoint.
+// Any response below 500 counts as alive; other transports are not probed.
+func HTTPProbe(timeout time.Duration) ProbeFunc {
+	client := &http.Client{Timeout: timeout}
+	return func(a *BaseAgent) error {
+		if a.GetTransport() != TransportHTTP {
+			return ErrProbeUnsupported
+		}
+		resp, err := client.Get(a.GetEndpoint())
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("health probe returned status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// HealthMonitor periodically updates the availability of every agent in a registry.
+// Agents that push heartbeats are judged by how recent their last heartbeat is;
+// every other agent is probed.
+type HealthMonitor struct {
+	Registry     AgentRegistry
+	Interval     time.Duration
+	HeartbeatTTL time.Duration // How long a heartbeat keeps an agent available
+	Probe        ProbeFunc     // Nil disables probing
+	stop         chan struct{}
+	done         chan struct{}
+	mu           sync.Mutex
+}
+
+// NewHealthMonitor creates a HealthMonitor that checks every interval, probes HTTP agents
+// and marks heartbeat agents unavailable after three missed intervals.
+func NewHealthMonitor(registry AgentRegistry, interval time.Duration) *HealthMonitor {
+	return &HealthMonitor{
+		Registry:     registry,
+		Interval:     interval,
+		HeartbeatTTL: 3 * interval,
+		Probe:        HTTPProbe(interval),
+	}
+}
+
+// CheckAll updates the availability of every registered agent once.
+func (m *HealthMonitor) CheckAll() {
+	now := time.Now()
+	for _, a := range m.Registry.List() {
+		if last := a.LastHeartbeat(); !last.IsZero() {
+			if m.HeartbeatTTL > 0 && now.Sub(last) > m.HeartbeatTTL {
+				a.SetAvailability(Unavailable)
+			}
+			continue
+		}
+
+		if m.Probe == nil {
+			continue
+		}
+		err := m.Probe(a)
+		if errors.Is(err, ErrProbeUnsupported) {
+			continue
+		}
+		if err != nil {
+			a.SetAvailability(Unavailable)
+		} else {
+			a.SetAvailability(Available)
+		}
+	}
+}
+
+// Heartbeat records a heartbeat pushed by the agent with the given ID.
+func (m *HealthMonitor) Heartbeat(id string) error {
+	a := m.Registry.GetByID(id)
+	if a == nil {
+		return fmt.Errorf("agent with ID '%s' is not registered", id)
+	}
+	a.RecordHeartbeat(time.Now())
+	return nil
+}
+
+// Start checks every agent immediately and then once per interval until Stop is called.
+func (m *HealthMonitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+
+	go func(stop chan struct{}, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(m.Interval)
+		defer ticker.Stop()
+
+		m.CheckAll()
+		for {
+			select {
+			case <-ticker.C:
+				m.CheckAll()
+			case <-stop:
+				return
+			}
+		}
+	}(m.stop, m.done)
+}
+
+// Stop ends the periodic checks and waits for the current one to finish.
+func (m *HealthMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+	m.stop = nil
+	m.done = nil
+}
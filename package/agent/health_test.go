@@ -0,0 +1,105 @@
+package agent_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"trace/package/agent"
+)
+
+// TestHealthMonitor_Probe tests that probed agents are marked available or unavailable.
+func TestHealthMonitor_Probe(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+
+	registry := agent.NewMemoryRegistry()
+	up := agent.NewBaseAgent("AG1", "Up", "Utility", healthy.URL, nil, nil)
+	down := agent.NewBaseAgent("AG2", "Down", "Utility", broken.URL, nil, nil)
+	local := agent.NewBaseAgent("AG3", "Local", "Utility", "cat", nil, nil)
+	local.Transport = agent.TransportSubprocess
+	for _, a := range []*agent.BaseAgent{up, down, local} {
+		registry.Register(a)
+	}
+
+	monitor := agent.NewHealthMonitor(registry, time.Second)
+	monitor.CheckAll()
+
+	if up.GetAvailability() != agent.Available {
+		t.Errorf("Expected %s to be Available, got %s", up.Name, up.GetAvailability())
+	}
+	if down.GetAvailability() != agent.Unavailable {
+		t.Errorf("Expected %s to be Unavailable, got %s", down.Name, down.GetAvailability())
+	}
+	if local.GetAvailability() != agent.Unknown {
+		t.Errorf("Expected unprobed %s to stay Unknown, got %s", local.Name, local.GetAvailability())
+	}
+	if !local.IsAvailable() {
+		t.Error("Expected agents of unknown availability to accept tasks")
+	}
+}
+
+// TestHealthMonitor_Heartbeat tests that heartbeats keep an agent available until they stop arriving.
+func TestHealthMonitor_Heartbeat(t *testing.T) {
+	registry := agent.NewMemoryRegistry()
+	pusher := agent.NewBaseAgent("AG1", "Pusher", "Utility", "http://unreachable.invalid", nil, nil)
+	registry.Register(pusher)
+
+	monitor := agent.NewHealthMonitor(registry, time.Second)
+	monitor.HeartbeatTTL = 50 * time.Millisecond
+	monitor.Probe = func(a *agent.BaseAgent) error {
+		t.Errorf("Agents sending heartbeats should not be probed")
+		return nil
+	}
+
+	if err := monitor.Heartbeat("AG1"); err != nil {
+		t.Fatalf("Heartbeat failed: %v", err)
+	}
+	monitor.CheckAll()
+	if pusher.GetAvailability() != agent.Available {
+		t.Errorf("Expected Available right after a heartbeat, got %s", pusher.GetAvailability())
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	monitor.CheckAll()
+	if pusher.GetAvailability() != agent.Unavailable {
+		t.Errorf("Expected Unavailable once the heartbeat expired, got %s", pusher.GetAvailability())
+	}
+
+	if err := monitor.Heartbeat("missing"); err == nil {
+		t.Error("Expected error for heartbeat from unknown agent, but got none")
+	}
+}
+
+// TestHealthMonitor_StartStop tests that the monitor runs checks in the background.
+func TestHealthMonitor_StartStop(t *testing.T) {
+	registry := agent.NewMemoryRegistry()
+	a := agent.NewBaseAgent("AG1", "Probed", "Utility", "http://example", nil, nil)
+	registry.Register(a)
+
+	checked := make(chan struct{}, 10)
+	monitor := agent.NewHealthMonitor(registry, 10*time.Millisecond)
+	monitor.Probe = func(a *agent.BaseAgent) error {
+		checked <- struct{}{}
+		return nil
+	}
+
+	monitor.Start()
+	select {
+	case <-checked:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the monitor to probe the agent")
+	}
+	monitor.Stop()
+	monitor.Stop()
+
+	if a.GetAvailability() != agent.Available {
+		t.Errorf("Expected Available after a successful probe, got %s", a.GetAvailability())
+	}
+}
@@ -0,0 +1,257 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// AgentRegistry stores the agents enrolled in a deployment.
+type AgentRegistry interface {
+	GetByID(id string) *BaseAgent
+	GetByName(name string) *BaseAgent
+	GetByType(agentType string) []*BaseAgent
+	List() []*BaseAgent
+	Register(a *BaseAgent) error
+	Update(a *BaseAgent) error
+	Unregister(id string) error
+}
+
+// ErrAlreadyRegistered is returned when an agent's ID or name is already taken by another agent.
+var ErrAlreadyRegistered = errors.New("already registered")
+
+// MemoryRegistry is an AgentRegistry kept in memory.
+type MemoryRegistry struct {
+	agents map[string]*BaseAgent // Mapping of agent ID to agent
+	mu     sync.RWMutex
+}
+
+// NewMemoryRegistry creates an empty MemoryRegistry.
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		agents: make(map[string]*BaseAgent),
+	}
+}
+
+// NewMockRegistry creates a MemoryRegistry holding the mock agents.
+func NewMockRegistry() *MemoryRegistry {
+	r := NewMemoryRegistry()
+	for _, a := range GetMockAgents() {
+		r.agents[a.ID] = a
+	}
+	return r
+}
+
+// GetByID returns the agent with the given ID, or nil if none is registered.
+func (r *MemoryRegistry) GetByID(id string) *BaseAgent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.agents[id]
+}
+
+// GetByName returns the agent with the given name, or nil if none is registered.
+func (r *MemoryRegistry) GetByName(name string) *BaseAgent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, a := range r.agents {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// GetByType returns every agent of the given type, ordered by ID.
+func (r *MemoryRegistry) GetByType(agentType string) []*BaseAgent {
+	matches := []*BaseAgent{}
+	for _, a := range r.List() {
+		if a.AgentType == agentType {
+			matches = append(matches, a)
+		}
+	}
+	return matches
+}
+
+// List returns every registered agent, ordered by ID.
+func (r *MemoryRegistry) List() []*BaseAgent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	agents := make([]*BaseAgent, 0, len(r.agents))
+	for _, a := range r.agents {
+		agents = append(agents, a)
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		return agents[i].ID < agents[j].ID
+	})
+	return agents
+}
+
+// Register adds an agent. IDs and names must be unique so that scripts can refer to agents unambiguously.
+func (r *MemoryRegistry) Register(a *BaseAgent) error {
+	if a == nil {
+		return errors.New("cannot register a nil agent")
+	}
+	if a.ID == "" || a.Name == "" {
+		return errors.New("agent ID and name are required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.agents[a.ID]; exists {
+		return fmt.Errorf("agent with ID '%s' is %w", a.ID, ErrAlreadyRegistered)
+	}
+	for _, existing := range r.agents {
+		if existing.Name == a.Name {
+			return fmt.Errorf("agent with name '%s' is %w", a.Name, ErrAlreadyRegistered)
+		}
+	}
+	r.agents[a.ID] = a
+	return nil
+}
+
+// Update replaces the registered agent that has the same ID, keeping its availability and last heartbeat.
+func (r *MemoryRegistry) Update(a *BaseAgent) error {
+	if a == nil {
+		return errors.New("cannot update a nil agent")
+	}
+	if a.ID == "" || a.Name == "" {
+		return errors.New("agent ID and name are required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, exists := r.agents[a.ID]
+	if !exists {
+		return fmt.Errorf("agent with ID '%s' is not registered", a.ID)
+	}
+	for id, existing := range r.agents {
+		if id != a.ID && existing.Name == a.Name {
+			return fmt.Errorf("agent with name '%s' is %w", a.Name, ErrAlreadyRegistered)
+		}
+	}
+	a.inheritHealth(previous)
+	r.agents[a.ID] = a
+	return nil
+}
+
+// Unregister removes the agent with the given ID.
+func (r *MemoryRegistry) Unregister(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.agents[id]; !exists {
+		return fmt.Errorf("agent with ID '%s' is not registered", id)
+	}
+	delete(r.agents, id)
+	return nil
+}
+
+// FileRegistry is an AgentRegistry persisted as a JSON array of agents.
+type FileRegistry struct {
+	*MemoryRegistry
+	path   string
+	fileMu sync.Mutex
+}
+
+// NewFileRegistry loads the agents stored at path. A missing file is treated as an empty registry.
+func NewFileRegistry(path string) (*FileRegistry, error) {
+	r := &FileRegistry{
+		MemoryRegistry: NewMemoryRegistry(),
+		path:           path,
+	}
+
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading agent registry '%s': %w", path, err)
+	}
+
+	var agents []*BaseAgent
+	if err := json.Unmarshal(contents, &agents); err != nil {
+		return nil, fmt.Errorf("error parsing agent registry '%s': %w", path, err)
+	}
+	for _, a := range agents {
+		if err := r.MemoryRegistry.Register(a); err != nil {
+			return nil, fmt.Errorf("error loading agent registry '%s': %w", path, err)
+		}
+	}
+	return r, nil
+}
+
+// Register adds an agent and writes the registry back to disk.
+func (r *FileRegistry) Register(a *BaseAgent) error {
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+	if err := r.MemoryRegistry.Register(a); err != nil {
+		return err
+	}
+	if err := r.save(); err != nil {
+		r.MemoryRegistry.Unregister(a.ID)
+		return err
+	}
+	return nil
+}
+
+// Update replaces an agent and writes the registry back to disk.
+func (r *FileRegistry) Update(a *BaseAgent) error {
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+	var previous *BaseAgent
+	if a != nil {
+		previous = r.MemoryRegistry.GetByID(a.ID)
+	}
+	if err := r.MemoryRegistry.Update(a); err != nil {
+		return err
+	}
+	if err := r.save(); err != nil {
+		r.MemoryRegistry.Update(previous)
+		return err
+	}
+	return nil
+}
+
+// Unregister removes an agent and writes the registry back to disk.
+func (r *FileRegistry) Unregister(id string) error {
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+	removed := r.MemoryRegistry.GetByID(id)
+	if err := r.MemoryRegistry.Unregister(id); err != nil {
+		return err
+	}
+	if err := r.save(); err != nil {
+		r.MemoryRegistry.Register(removed)
+		return err
+	}
+	return nil
+}
+
+// save writes every agent to a temporary file and renames it over the registry file.
+func (r *FileRegistry) save() error {
+	contents, err := json.MarshalIndent(r.MemoryRegistry.List(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding agent registry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("error writing agent registry '%s': %w", r.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing agent registry '%s': %w", r.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing agent registry '%s': %w", r.path, err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("error writing agent registry '%s': %w", r.path, err)
+	}
+	return nil
+}
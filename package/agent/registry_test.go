@@ -0,0 +1,107 @@
+package agent_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"trace/package/agent"
+)
+
+// TestMemoryRegistry tests registering, looking up and unregistering agents.
+func TestMemoryRegistry(t *testing.T) {
+	registry := agent.NewMemoryRegistry()
+
+	weather := agent.NewBaseAgent("AG1", "WeatherA", "Utility", "http://a", nil, nil)
+	backup := agent.NewBaseAgent("AG2", "WeatherB", "Utility", "http://b", nil, nil)
+	flights := agent.NewBaseAgent("AG3", "Flights", "Travel", "http://c", nil, nil)
+
+	for _, a := range []*agent.BaseAgent{weather, backup, flights} {
+		if err := registry.Register(a); err != nil {
+			t.Fatalf("Register(%s) failed: %v", a.ID, err)
+		}
+	}
+
+	if got := registry.GetByID("AG2"); got != backup {
+		t.Errorf("Expected GetByID to return %v, got %v", backup, got)
+	}
+	if got := registry.GetByName("Flights"); got != flights {
+		t.Errorf("Expected GetByName to return %v, got %v", flights, got)
+	}
+	if got := registry.GetByType("Utility"); !reflect.DeepEqual(got, []*agent.BaseAgent{weather, backup}) {
+		t.Errorf("Expected GetByType to return both utility agents, got %v", got)
+	}
+	if got := registry.GetByName("Missing"); got != nil {
+		t.Errorf("Expected nil for unknown agent, got %v", got)
+	}
+
+	if err := registry.Register(agent.NewBaseAgent("AG1", "Other", "Utility", "", nil, nil)); err == nil {
+		t.Error("Expected error for duplicate ID, but got none")
+	}
+	if err := registry.Register(agent.NewBaseAgent("AG9", "Flights", "Travel", "", nil, nil)); err == nil {
+		t.Error("Expected error for duplicate name, but got none")
+	}
+
+	moved := agent.NewBaseAgent("AG2", "WeatherB", "Utility", "http://b2", nil, nil)
+	if err := registry.Update(moved); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if got := registry.GetByID("AG2"); got.GetEndpoint() != "http://b2" {
+		t.Errorf("Expected updated endpoint, got %s", got.GetEndpoint())
+	}
+	if err := registry.Update(agent.NewBaseAgent("AG2", "Flights", "Utility", "", nil, nil)); err == nil {
+		t.Error("Expected error updating to a taken name, but got none")
+	}
+	if err := registry.Update(agent.NewBaseAgent("AG9", "Nobody", "Utility", "", nil, nil)); err == nil {
+		t.Error("Expected error updating a missing agent, but got none")
+	}
+
+	if err := registry.Unregister("AG1"); err != nil {
+		t.Fatalf("Unregister failed: %v", err)
+	}
+	if got := registry.GetByID("AG1"); got != nil {
+		t.Errorf("Expected unregistered agent to be gone, got %v", got)
+	}
+	if err := registry.Unregister("AG1"); err == nil {
+		t.Error("Expected error unregistering a missing agent, but got none")
+	}
+}
+
+// TestFileRegistry tests that registrations persist across loads.
+func TestFileRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agents.json")
+
+	registry, err := agent.NewFileRegistry(path)
+	if err != nil {
+		t.Fatalf("NewFileRegistry failed: %v", err)
+	}
+
+	tracker := agent.NewBaseAgent("AG127", "PackageTracker", "Logistics", "http://tracker",
+		map[string]interface{}{"tracking_number": "[[tracking_number]]"}, []string{"Track Package"})
+	tracker.Transport = agent.TransportSubprocess
+	if err := registry.Register(tracker); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := registry.Register(agent.NewBaseAgent("AG128", "Temp", "Logistics", "http://temp", nil, nil)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := registry.Unregister("AG128"); err != nil {
+		t.Fatalf("Unregister failed: %v", err)
+	}
+
+	reloaded, err := agent.NewFileRegistry(path)
+	if err != nil {
+		t.Fatalf("Reloading registry failed: %v", err)
+	}
+
+	agents := reloaded.List()
+	if len(agents) != 1 {
+		t.Fatalf("Expected 1 agent after reload, got %d", len(agents))
+	}
+	got := agents[0]
+	if got.ID != tracker.ID || got.Name != tracker.Name || got.Endpoint != tracker.Endpoint || got.GetTransport() != agent.TransportSubprocess {
+		t.Errorf("Reloaded agent does not match: %+v", got)
+	}
+	if !reflect.DeepEqual(got.JsonBody, tracker.JsonBody) {
+		t.Errorf("Expected JSON body %v, got %v", tracker.JsonBody, got.JsonBody)
+	}
+}
@@ -0,0 +1,208 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"trace/package/parser"
+)
+
+// Graph is a directed graph of dependencies between named statements. Nodes keep the order they were added in.
+type Graph struct {
+	nodes   []string
+	deps    map[string][]string // Node to the nodes it waits for
+	reasons map[edge][]string   // Why each edge exists, for review
+}
+
+// edge is a node and a node it waits for.
+type edge struct {
+	node       string
+	dependency string
+}
+
+// NewGraph creates an empty graph.
+func NewGraph() *Graph {
+	return &Graph{deps: make(map[string][]string), reasons: make(map[edge][]string)}
+}
+
+// DependencyGraph builds the graph of a RUNCON block: one node per statement, keyed as in the block's Keys,
+// and an edge for each name in the AFTER clause of a task.
+func DependencyGraph(block *parser.RunConBlock) *Graph {
+	return declaredGraph(block.Keys, block.Statements)
+}
+
+// declaredGraph builds the graph of keyed statements from the AFTER clauses of their tasks.
+func declaredGraph(keys []string, statements []parser.Statement) *Graph {
+	g := NewGraph()
+	for _, key := range keys {
+		g.AddNode(key)
+	}
+	for i, stmt := range statements {
+		if t, ok := stmt.(*parser.Task); ok {
+			for _, dependency := range t.After {
+				g.AddEdge(keys[i], dependency, "AFTER")
+			}
+		}
+	}
+	return g
+}
+
+// AddNode adds a node with no dependencies. Adding a node twice has no effect.
+func (g *Graph) AddNode(name string) {
+	if _, exists := g.deps[name]; exists {
+		return
+	}
+	g.nodes = append(g.nodes, name)
+	g.deps[name] = nil
+}
+
+// AddEdge records that node waits for dependency, and why, adding either node if it is missing.
+func (g *Graph) AddEdge(node string, dependency string, reasons ...string) {
+	g.AddNode(node)
+	g.AddNode(dependency)
+	e := edge{node: node, dependency: dependency}
+	for _, reason := range reasons {
+		if !contains(g.reasons[e], reason) {
+			g.reasons[e] = append(g.reasons[e], reason)
+		}
+	}
+	if !contains(g.deps[node], dependency) {
+		g.deps[node] = append(g.deps[node], dependency)
+	}
+}
+
+// Nodes returns every node in the order it was added.
+func (g *Graph) Nodes() []string {
+	return g.nodes
+}
+
+// Dependencies returns the nodes the given node waits for.
+func (g *Graph) Dependencies(node string) []string {
+	return g.deps[node]
+}
+
+// Reasons returns why node waits for dependency, in the order the reasons were added.
+func (g *Graph) Reasons(node string, dependency string) []string {
+	return g.reasons[edge{node: node, dependency: dependency}]
+}
+
+// Dependents returns the nodes that wait for the given node, in the order they were added.
+func (g *Graph) Dependents(node string) []string {
+	var dependents []string
+	for _, n := range g.nodes {
+		for _, dependency := range g.deps[n] {
+			if dependency == node {
+				dependents = append(dependents, n)
+				break
+			}
+		}
+	}
+	return dependents
+}
+
+// DependsOn reports whether node waits for dependency, directly or through other nodes.
+func (g *Graph) DependsOn(node string, dependency string) bool {
+	seen := make(map[string]bool)
+	var visit func(n string) bool
+	visit = func(n string) bool {
+		for _, d := range g.deps[n] {
+			if d == dependency {
+				return true
+			}
+			if !seen[d] {
+				seen[d] = true
+				if visit(d) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return visit(node)
+}
+
+// Cycle returns a cycle of dependencies as the path that closes it, such as [A B A], or nil if the graph
+// has none.
+func (g *Graph) Cycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g.nodes))
+	var path []string
+
+	var visit func(node string) []string
+	visit = func(node string) []string {
+		state[node] = visiting
+		path = append(path, node)
+		for _, dependency := range g.deps[node] {
+			switch state[dependency] {
+			case visiting:
+				// The cycle is the part of the path from the first visit of dependency
+				for i, n := range path {
+					if n == dependency {
+						return append(append([]string(nil), path[i:]...), dependency)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dependency); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[node] = visited
+		return nil
+	}
+
+	for _, node := range g.nodes {
+		if state[node] == unvisited {
+			if cycle := visit(node); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// Check returns an error naming a cycle in the graph, such as "dependency cycle: A -> B -> A" when A waits
+// for B and B for A, or nil if there is none.
+func (g *Graph) Check() error {
+	if cycle := g.Cycle(); cycle != nil {
+		return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// String returns the graph one node per line, in order, with the nodes it waits for and why:
+//
+//	ScheduleFlight
+//	BookHotel after ScheduleFlight (reads flightInfo)
+func (g *Graph) String() string {
+	var b strings.Builder
+	for _, node := range g.nodes {
+		b.WriteString(node)
+		for i, dependency := range g.deps[node] {
+			if i == 0 {
+				b.WriteString(" after ")
+			} else {
+				b.WriteString(", ")
+			}
+			b.WriteString(dependency)
+			if reasons := g.Reasons(node, dependency); len(reasons) > 0 {
+				fmt.Fprintf(&b, " (%s)", strings.Join(reasons, ", "))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
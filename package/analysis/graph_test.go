@@ -0,0 +1,67 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+	"trace/package/analysis"
+	"trace/package/parser"
+)
+
+// TestDependencyGraph tests the graph built from the AFTER clauses of a RUNCON block.
+func TestDependencyGraph(t *testing.T) {
+	p := parser.NewParser(parser.NewLexer(`START
+RUNCON {
+    TASK ScheduleFlight AGENT FlightGetter PARAMETERS () ;
+    TASK CheckWeather AGENT WeatherChecker PARAMETERS () ;
+    TASK BookHotel AGENT RoomBooker PARAMETERS () AFTER ScheduleFlight ;
+    TASK PlanDay AGENT Planner PARAMETERS () AFTER BookHotel, CheckWeather ;
+}
+END`))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	g := analysis.DependencyGraph(pr.Statements[0].(*parser.RunConBlock))
+	if expected := []string{"ScheduleFlight", "CheckWeather", "BookHotel", "PlanDay"}; !reflect.DeepEqual(g.Nodes(), expected) {
+		t.Errorf("Expected nodes %v, got %v", expected, g.Nodes())
+	}
+	if expected := []string{"BookHotel", "CheckWeather"}; !reflect.DeepEqual(g.Dependencies("PlanDay"), expected) {
+		t.Errorf("Expected PlanDay to depend on %v, got %v", expected, g.Dependencies("PlanDay"))
+	}
+	if expected := []string{"BookHotel"}; !reflect.DeepEqual(g.Dependents("ScheduleFlight"), expected) {
+		t.Errorf("Expected %v to depend on ScheduleFlight, got %v", expected, g.Dependents("ScheduleFlight"))
+	}
+	if err := g.Check(); err != nil {
+		t.Errorf("Expected no cycle, got %v", err)
+	}
+}
+
+// TestGraph_Cycle tests that cycles are found and reported along the path that closes them.
+func TestGraph_Cycle(t *testing.T) {
+	g := analysis.NewGraph()
+	g.AddNode("Start")
+	g.AddEdge("A", "Start")
+	g.AddEdge("B", "A")
+	g.AddEdge("C", "B")
+	if cycle := g.Cycle(); cycle != nil {
+		t.Fatalf("Expected no cycle, got %v", cycle)
+	}
+	if !g.DependsOn("C", "Start") || g.DependsOn("Start", "C") {
+		t.Errorf("Expected C to wait for Start through A and B, and not the other way round")
+	}
+
+	g.AddEdge("A", "C")
+	if expected := []string{"A", "C", "B", "A"}; !reflect.DeepEqual(g.Cycle(), expected) {
+		t.Errorf("Expected cycle %v, got %v", expected, g.Cycle())
+	}
+	if err := g.Check(); err == nil || err.Error() != "dependency cycle: A -> C -> B -> A" {
+		t.Errorf("Expected a cycle error, got %v", err)
+	}
+
+	self := analysis.NewGraph()
+	self.AddEdge("A", "A")
+	if expected := []string{"A", "A"}; !reflect.DeepEqual(self.Cycle(), expected) {
+		t.Errorf("Expected self-cycle %v, got %v", expected, self.Cycle())
+	}
+}
@@ -0,0 +1,112 @@
+package analysis
+
+import (
+	"sort"
+	"trace/package/parser"
+)
+
+// Access is the global data a statement reads and writes, anywhere inside it. Names are sorted.
+type Access struct {
+	Reads  []string
+	Writes []string
+}
+
+// Accesses returns what a statement reads and writes: the references in task parameters, conditions and
+// FOREACH lists, and task outputs and FOREACH collect targets. Compensations are not included, as they
+// only run once the script has failed.
+func Accesses(stmt parser.Statement) Access {
+	reads := make(map[string]bool)
+	writes := make(map[string]bool)
+	parser.Inspect(stmt, func(stmt parser.Statement) bool {
+		addOwnAccesses(stmt, nil, reads, writes)
+		// The only child of a task is its compensation
+		_, isTask := stmt.(*parser.Task)
+		return !isTask
+	})
+	return Access{Reads: sortedKeys(reads), Writes: sortedKeys(writes)}
+}
+
+// addOwnAccesses adds what a statement itself reads and writes, leaving out its children and the given
+// local variables: the references and output of a task, the condition of an IF or loop, and the list and
+// collect target of a FOREACH.
+func addOwnAccesses(stmt parser.Statement, locals map[string]bool, reads map[string]bool, writes map[string]bool) {
+	read := func(name string) {
+		if !locals[name] {
+			reads[name] = true
+		}
+	}
+	readCondition := func(c *parser.Condition) {
+		for _, operand := range []parser.Parameter{c.Left, c.Right} {
+			if operand.IsReference() {
+				read(operand.Value)
+			}
+		}
+	}
+
+	switch s := stmt.(type) {
+	case *parser.Task:
+		for key, param := range s.Parameters {
+			if key != "OUTPUT" && param.IsReference() {
+				read(param.Value)
+			}
+		}
+		if output, ok := s.Output(); ok && !locals[output] {
+			writes[output] = true
+		}
+	case *parser.IfBlock:
+		readCondition(s.Condition)
+	case *parser.LoopBlock:
+		readCondition(s.Condition)
+	case *parser.ForEachBlock:
+		read(s.List)
+		if s.CollectVar != "" && !locals[s.CollectInto] {
+			writes[s.CollectInto] = true
+		}
+	}
+}
+
+// InferGraph builds the graph of an AUTO block. Each statement waits for the earlier statements of the block
+// that write data it reads, that read data it writes, or that write the same data, so that the block ends
+// with the same data as if it ran in order. AFTER clauses add their own edges.
+func InferGraph(block *parser.AutoBlock) *Graph {
+	g := declaredGraph(block.Keys, block.Statements)
+	accesses := make([]Access, len(block.Statements))
+	for i, stmt := range block.Statements {
+		accesses[i] = Accesses(stmt)
+	}
+
+	for j := range block.Statements {
+		for i := 0; i < j; i++ {
+			for _, name := range intersect(accesses[i].Writes, accesses[j].Reads) {
+				g.AddEdge(block.Keys[j], block.Keys[i], "reads "+name)
+			}
+			for _, name := range intersect(accesses[i].Reads, accesses[j].Writes) {
+				g.AddEdge(block.Keys[j], block.Keys[i], "overwrites "+name)
+			}
+			for _, name := range intersect(accesses[i].Writes, accesses[j].Writes) {
+				g.AddEdge(block.Keys[j], block.Keys[i], "also writes "+name)
+			}
+		}
+	}
+	return g
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// intersect returns the names in both sorted lists, in order.
+func intersect(a []string, b []string) []string {
+	var both []string
+	for _, name := range a {
+		if contains(b, name) {
+			both = append(both, name)
+		}
+	}
+	return both
+}
@@ -0,0 +1,90 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+	"trace/package/analysis"
+	"trace/package/parser"
+)
+
+// parseAuto parses a script and returns its first statement, which must be an AUTO block.
+func parseAuto(t *testing.T, input string) *parser.AutoBlock {
+	t.Helper()
+	p := parser.NewParser(parser.NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+	return pr.Statements[0].(*parser.AutoBlock)
+}
+
+// TestAccesses tests the data read and written by tasks and blocks, leaving out compensations.
+func TestAccesses(t *testing.T) {
+	auto := parseAuto(t, `START
+AUTO {
+    TASK BookHotel AGENT RoomBooker PARAMETERS (location=location, date="2024-05-15", OUTPUT=hotelInfo)
+        COMPENSATE WITH TASK CancelHotel AGENT RoomBooker PARAMETERS (booking=hotelInfo, OUTPUT=refund) ;
+    FOREACH city IN cities COLLECT forecast INTO forecasts {
+        IF city != home {
+            TASK CheckWeather AGENT WeatherChecker PARAMETERS (location=city, OUTPUT=forecast) ;
+        }
+    }
+}
+END`)
+
+	tests := []struct {
+		key      string
+		expected analysis.Access
+	}{
+		{"BookHotel", analysis.Access{Reads: []string{"location"}, Writes: []string{"hotelInfo"}}},
+		{"FOREACH_0", analysis.Access{Reads: []string{"cities", "city", "home"}, Writes: []string{"forecast", "forecasts"}}},
+	}
+	for _, test := range tests {
+		if got := analysis.Accesses(auto.Statement(test.key)); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.key, test.expected, got)
+		}
+	}
+}
+
+// TestInferGraph tests that statements wait only for the earlier statements whose data they share.
+func TestInferGraph(t *testing.T) {
+	auto := parseAuto(t, `START
+AUTO {
+    TASK ScheduleFlight AGENT FlightGetter PARAMETERS (origin=origin, OUTPUT=flightInfo) ;
+    TASK CheckWeather AGENT WeatherChecker PARAMETERS (location=destination, OUTPUT=weather) ;
+    TASK BookHotel AGENT RoomBooker PARAMETERS (flight=flightInfo, OUTPUT=hotelInfo) ;
+    TASK PlanDay AGENT Planner PARAMETERS (hotel=hotelInfo, weather=weather, OUTPUT=plan) ;
+    TASK Rebook AGENT FlightGetter PARAMETERS (OUTPUT=flightInfo) ;
+    TASK Notify AGENT Notifier PARAMETERS () AFTER CheckWeather ;
+}
+END`)
+
+	expected := `ScheduleFlight
+CheckWeather
+BookHotel after ScheduleFlight (reads flightInfo)
+PlanDay after CheckWeather (reads weather), BookHotel (reads hotelInfo)
+Rebook after ScheduleFlight (also writes flightInfo), BookHotel (overwrites flightInfo)
+Notify after CheckWeather (AFTER)
+`
+	g := analysis.InferGraph(auto)
+	if got := g.String(); got != expected {
+		t.Errorf("Unexpected graph:\nExpected:\n%s\nGot:\n%s", expected, got)
+	}
+	if err := g.Check(); err != nil {
+		t.Errorf("Expected no cycle, got %v", err)
+	}
+}
+
+// TestInferGraph_Cycle tests that an AFTER clause against the order of the data makes a cycle.
+func TestInferGraph_Cycle(t *testing.T) {
+	auto := parseAuto(t, `START
+AUTO {
+    TASK ScheduleFlight AGENT FlightGetter PARAMETERS (OUTPUT=flightInfo) AFTER BookHotel ;
+    TASK BookHotel AGENT RoomBooker PARAMETERS (flight=flightInfo) ;
+}
+END`)
+
+	if err := analysis.InferGraph(auto).Check(); err == nil || err.Error() != "dependency cycle: ScheduleFlight -> BookHotel -> ScheduleFlight" {
+		t.Errorf("Expected a cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"trace/package/parser"
+)
+
+// Kinds of race between two tasks.
+const (
+	WriteWrite = "write/write" // Both tasks write the data, so the value left depends on which finishes last
+	ReadWrite  = "read/write"  // One task reads data the other writes, so what it reads depends on timing
+)
+
+// Race is a piece of global data that two statements may access at the same time, with at least one of them
+// writing it. The statements are tasks, IF and loop blocks reading their condition, and FOREACH blocks
+// reading their list or writing their collect target. Writer and Other are the same statement when the race
+// is between iterations of a concurrent FOREACH.
+type Race struct {
+	Kind   string
+	Data   string
+	Writer parser.Statement // A statement that writes Data
+	Other  parser.Statement // A statement that also writes Data for WriteWrite, or reads it for ReadWrite
+	Block  parser.Statement // The RUNCON or concurrent FOREACH block the statements run concurrently in
+}
+
+// Span returns the source range of the statement of the race that comes first in the script.
+func (r Race) Span() parser.Span {
+	if before(r.Other.GetSpan().Start, r.Writer.GetSpan().Start) {
+		return r.Other.GetSpan()
+	}
+	return r.Writer.GetSpan()
+}
+
+// String describes the race, for example:
+//
+//	write/write race on 'hotelInfo': task 'BookHotel' at 3:9 and task 'Upgrade' at 4:9 both write it in the RUNCON block at 2:5
+func (r Race) String() string {
+	block := fmt.Sprintf("the %s block at %s", blockKeyword(r.Block), r.Block.GetSpan().Start)
+	switch {
+	case r.Writer == r.Other && r.Kind == WriteWrite:
+		return fmt.Sprintf("%s race on '%s': %s writes it from concurrent iterations of %s", r.Kind, r.Data, describeAccess(r.Writer), block)
+	case r.Writer == r.Other:
+		return fmt.Sprintf("%s race on '%s': %s reads and writes it from concurrent iterations of %s", r.Kind, r.Data, describeAccess(r.Writer), block)
+	case r.Kind == WriteWrite:
+		return fmt.Sprintf("%s race on '%s': %s and %s both write it in %s", r.Kind, r.Data, describeAccess(r.Writer), describeAccess(r.Other), block)
+	default:
+		return fmt.Sprintf("%s race on '%s': %s writes it while %s reads it in %s", r.Kind, r.Data, describeAccess(r.Writer), describeAccess(r.Other), block)
+	}
+}
+
+// FindRaces reports the statements of a script that may access the same global data at the same time, with
+// at least one of them writing it: statements nested in different statements of a RUNCON block that are not
+// ordered by AFTER, and statements in the body of a concurrent FOREACH, which race with the same statements
+// in other iterations. Accesses are those of Accesses, counted for the task, condition or FOREACH that makes them.
+// Variables local to a FOREACH or CATCH inside the concurrent statements are not shared, so they never
+// race. RUNANY and RUNQUORUM blocks are left out, as each of their statements writes to its own copy of the
+// data, and AUTO blocks order conflicting statements themselves. Races are returned in source order.
+func FindRaces(pr *parser.ParentRequest) []Race {
+	var races []Race
+	seen := make(map[raceKey]bool)
+	add := func(kind string, data string, writer parser.Statement, other parser.Statement, block parser.Statement) {
+		// A pair of statements can run concurrently in more than one block; the outermost is reported
+		key := raceKey{kind: kind, data: data, writer: writer, other: other}
+		if kind == WriteWrite && before(other.GetSpan().Start, writer.GetSpan().Start) {
+			key.writer, key.other = other, writer
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		races = append(races, Race{Kind: kind, Data: data, Writer: writer, Other: other, Block: block})
+	}
+	compare := func(a statementAccess, b statementAccess, block parser.Statement) {
+		for _, name := range intersect(a.writes, b.writes) {
+			add(WriteWrite, name, a.stmt, b.stmt, block)
+		}
+		for _, name := range intersect(a.writes, b.reads) {
+			add(ReadWrite, name, a.stmt, b.stmt, block)
+		}
+		if a.stmt != b.stmt {
+			for _, name := range intersect(b.writes, a.reads) {
+				add(ReadWrite, name, b.stmt, a.stmt, block)
+			}
+		}
+	}
+
+	parser.InspectAll(pr.Statements, func(stmt parser.Statement) bool {
+		switch s := stmt.(type) {
+		case *parser.RunConBlock:
+			g := DependencyGraph(s)
+			branches := make([][]statementAccess, len(s.Statements))
+			for i, child := range s.Statements {
+				branches[i] = statementAccesses([]parser.Statement{child}, nil)
+			}
+			for j := range s.Statements {
+				for i := 0; i < j; i++ {
+					if g.DependsOn(s.Keys[i], s.Keys[j]) || g.DependsOn(s.Keys[j], s.Keys[i]) {
+						continue
+					}
+					for _, a := range branches[i] {
+						for _, b := range branches[j] {
+							compare(a, b, s)
+						}
+					}
+				}
+			}
+		case *parser.ForEachBlock:
+			if !s.Concurrent {
+				break
+			}
+			// Each iteration has its own loop and collect variables
+			locals := map[string]bool{s.Item: true}
+			if s.CollectVar != "" {
+				locals[s.CollectVar] = true
+			}
+			body := statementAccesses(s.Body, locals)
+			for j := range body {
+				for i := 0; i <= j; i++ {
+					compare(body[i], body[j], s)
+				}
+			}
+		}
+		return true
+	})
+
+	sort.SliceStable(races, func(i, j int) bool {
+		a, b := races[i].Span().Start, races[j].Span().Start
+		if a != b {
+			return before(a, b)
+		}
+		return races[i].Data < races[j].Data
+	})
+	return races
+}
+
+// raceKey identifies a race regardless of the block it was found in.
+type raceKey struct {
+	kind   string
+	data   string
+	writer parser.Statement
+	other  parser.Statement
+}
+
+// statementAccess is the global data a single statement reads and writes itself, leaving out its children.
+type statementAccess struct {
+	stmt   parser.Statement
+	reads  []string
+	writes []string
+}
+
+// statementAccesses returns what each statement in the statements and their children reads and writes
+// itself, in source order, leaving out the given local variables and those of the FOREACH loops and CATCH
+// bodies inside the statements. Statements that access no global data are left out.
+func statementAccesses(statements []parser.Statement, locals map[string]bool) []statementAccess {
+	var accesses []statementAccess
+	parser.WalkAll(accessVisitor{locals: locals, accesses: &accesses}, statements)
+	return accesses
+}
+
+// accessVisitor collects the accesses of statements with the variables of the enclosing FOREACH loops and
+// CATCH bodies in scope.
+type accessVisitor struct {
+	locals   map[string]bool
+	accesses *[]statementAccess
+}
+
+func (v accessVisitor) Visit(stmt parser.Statement) parser.Visitor {
+	if stmt != nil {
+		reads := make(map[string]bool)
+		writes := make(map[string]bool)
+		addOwnAccesses(stmt, v.locals, reads, writes)
+		if len(reads) > 0 || len(writes) > 0 {
+			*v.accesses = append(*v.accesses, statementAccess{stmt: stmt, reads: sortedKeys(reads), writes: sortedKeys(writes)})
+		}
+	}
+
+	switch n := stmt.(type) {
+	case *parser.Task:
+		// Compensations run one at a time once the script has failed, so they never race
+		return nil
+	case *parser.ForEachBlock:
+		return v.with(n.Item, n.CollectVar)
+	case *parser.TryBlock:
+		// Only the CATCH body sees the failed task and error message
+		parser.WalkAll(v, n.Body)
+		parser.WalkAll(v.with(parser.CatchTaskVar, parser.CatchMessageVar), n.Catch)
+		parser.WalkAll(v, n.Finally)
+		return nil
+	}
+	return v
+}
+
+// with returns a visitor with the given names also in scope. Empty names are ignored.
+func (v accessVisitor) with(names ...string) accessVisitor {
+	locals := make(map[string]bool, len(v.locals)+len(names))
+	for name := range v.locals {
+		locals[name] = true
+	}
+	for _, name := range names {
+		if name != "" {
+			locals[name] = true
+		}
+	}
+	return accessVisitor{locals: locals, accesses: v.accesses}
+}
+
+// blockKeyword returns the keyword that opens a block, with CONCURRENT for concurrent FOREACH loops.
+func blockKeyword(stmt parser.Statement) string {
+	switch s := stmt.(type) {
+	case *parser.RunConBlock:
+		return "RUNCON"
+	case *parser.ForEachBlock:
+		if s.Concurrent {
+			return "FOREACH CONCURRENT"
+		}
+		return "FOREACH"
+	}
+	return fmt.Sprintf("%T", stmt)
+}
+
+// describeAccess names the statement that accesses data in a race.
+func describeAccess(stmt parser.Statement) string {
+	switch s := stmt.(type) {
+	case *parser.Task:
+		return fmt.Sprintf("task '%s' at %s", s.TaskName, s.Span.Start)
+	case *parser.IfBlock:
+		return fmt.Sprintf("the IF condition at %s", s.Span.Start)
+	case *parser.LoopBlock:
+		return fmt.Sprintf("the %s condition at %s", s.Keyword(), s.Span.Start)
+	case *parser.ForEachBlock:
+		return fmt.Sprintf("the FOREACH loop at %s", s.Span.Start)
+	}
+	return fmt.Sprintf("%T at %s", stmt, stmt.GetSpan().Start)
+}
+
+func before(a parser.Position, b parser.Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
@@ -0,0 +1,95 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+	"trace/package/analysis"
+	"trace/package/parser"
+)
+
+// TestFindRaces tests that tasks sharing data across concurrent branches and iterations are reported, and that
+// tasks ordered by AFTER or writing only loop variables are not.
+func TestFindRaces(t *testing.T) {
+	p := parser.NewParser(parser.NewLexer(`START
+RUNCON {
+    TASK BookHotel AGENT RoomBooker PARAMETERS (location=location, OUTPUT=hotelInfo) ;
+    RUNSEQ {
+        TASK Upgrade AGENT RoomBooker PARAMETERS (OUTPUT=hotelInfo) ;
+        TASK Notify AGENT Mailer PARAMETERS (body=flightInfo) ;
+    }
+    TASK ScheduleFlight AGENT FlightGetter PARAMETERS (OUTPUT=flightInfo) ;
+    TASK Confirm AGENT Mailer PARAMETERS (body=hotelInfo) AFTER BookHotel ;
+    FOREACH city IN cities COLLECT forecast INTO forecasts {
+        TASK CheckWeather AGENT WeatherChecker PARAMETERS (location=city, OUTPUT=forecast) ;
+    }
+}
+FOREACH city IN cities CONCURRENT {
+    TASK Track AGENT PackageTracker PARAMETERS (tracking=city, OUTPUT=packageStatus) ;
+}
+FOREACH city IN cities {
+    TASK Log AGENT Mailer PARAMETERS (body=city, OUTPUT=lastCity) ;
+}
+END`))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	var got []string
+	for _, race := range analysis.FindRaces(pr) {
+		got = append(got, race.String())
+	}
+	expected := []string{
+		"write/write race on 'hotelInfo': task 'BookHotel' at 3:5 and task 'Upgrade' at 5:9 both write it in the RUNCON block at 2:1",
+		"read/write race on 'hotelInfo': task 'Upgrade' at 5:9 writes it while task 'Confirm' at 9:5 reads it in the RUNCON block at 2:1",
+		"read/write race on 'flightInfo': task 'ScheduleFlight' at 8:5 writes it while task 'Notify' at 6:9 reads it in the RUNCON block at 2:1",
+		"write/write race on 'packageStatus': task 'Track' at 15:5 writes it from concurrent iterations of the FOREACH CONCURRENT block at 14:1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected races:\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
+
+// TestFindRaces_BlockAccesses tests that conditions and FOREACH lists and collect targets race with tasks like
+// task parameters and outputs do.
+func TestFindRaces_BlockAccesses(t *testing.T) {
+	p := parser.NewParser(parser.NewLexer(`START
+RUNCON {
+    TASK Ship AGENT PackageTracker PARAMETERS (OUTPUT=status) ;
+    UNTIL status == "done" MAX 5 {
+        TASK Poll AGENT PackageTracker PARAMETERS () ;
+    }
+}
+RUNCON {
+    TASK Refresh AGENT Planner PARAMETERS (OUTPUT=cities) ;
+    FOREACH city IN cities {
+        TASK CheckWeather AGENT WeatherChecker PARAMETERS (location=city) ;
+    }
+}
+RUNCON {
+    FOREACH city IN cities COLLECT forecast INTO forecasts {
+        TASK Forecast AGENT WeatherChecker PARAMETERS (location=city, OUTPUT=forecast) ;
+    }
+    IF forecasts CONTAINS "rain" {
+        TASK Warn AGENT Mailer PARAMETERS () ;
+    }
+}
+END`))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	var got []string
+	for _, race := range analysis.FindRaces(pr) {
+		got = append(got, race.String())
+	}
+	expected := []string{
+		"read/write race on 'status': task 'Ship' at 3:5 writes it while the UNTIL condition at 4:5 reads it in the RUNCON block at 2:1",
+		"read/write race on 'cities': task 'Refresh' at 9:5 writes it while the FOREACH loop at 10:5 reads it in the RUNCON block at 8:1",
+		"read/write race on 'forecasts': the FOREACH loop at 15:5 writes it while the IF condition at 18:5 reads it in the RUNCON block at 14:1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected races:\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
@@ -0,0 +1,224 @@
+package enrollment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"trace/package/agent"
+	"trace/package/utils/template"
+)
+
+// EnrollmentRequest is the body accepted when registering or updating an agent.
+type EnrollmentRequest struct {
+	ID           string                 `json:"id"`
+	Name         string                 `json:"name"`
+	AgentType    string                 `json:"agentType"`
+	Endpoint     string                 `json:"endpoint"`
+	Transport    string                 `json:"transport,omitempty"`
+	JsonBody     map[string]interface{} `json:"jsonBody"`
+	Capabilities []string               `json:"capabilities"`
+}
+
+// EnrollmentResponse describes an enrolled agent together with the placeholders its template expects.
+type EnrollmentResponse struct {
+	Agent        *agent.BaseAgent `json:"agent"`
+	Placeholders []string         `json:"placeholders"`
+	Availability string           `json:"availability"`
+}
+
+// errorResponse is the body returned for every failed request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server exposes an agent registry over HTTP so agents can be enrolled without recompiling.
+//
+//	GET    /agents       list enrolled agents
+//	POST   /agents       register an agent
+//	PUT    /agents/{id}  update an agent
+//	DELETE /agents/{id}  deregister an agent
+//	POST   /agents/{id}/heartbeat  record that the agent is alive
+type Server struct {
+	Registry agent.AgentRegistry
+	Health   *agent.HealthMonitor // Records the heartbeats agents send; nil disables the heartbeat route
+	mux      *http.ServeMux
+}
+
+// NewServer creates a Server backed by the given registry that records heartbeats with the given monitor.
+// Without a monitor, heartbeats are answered with 501 Not Implemented.
+func NewServer(registry agent.AgentRegistry, health *agent.HealthMonitor) *Server {
+	s := &Server{
+		Registry: registry,
+		Health:   health,
+		mux:      http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /agents", s.handleList)
+	s.mux.HandleFunc("POST /agents", s.handleRegister)
+	s.mux.HandleFunc("PUT /agents/{id}", s.handleUpdate)
+	s.mux.HandleFunc("DELETE /agents/{id}", s.handleDeregister)
+	s.mux.HandleFunc("POST /agents/{id}/heartbeat", s.handleHeartbeat)
+	return s
+}
+
+// ServeHTTP dispatches requests to the enrollment handlers.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	responses := []EnrollmentResponse{}
+	for _, a := range s.Registry.List() {
+		placeholders, err := template.Placeholders(a.GetJsonBody())
+		if err != nil {
+			placeholders = []string{}
+		}
+		responses = append(responses, newResponse(a, placeholders))
+	}
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	a, placeholders, err := decodeAgent(r, "")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// The registry checks for duplicates atomically, so concurrent registrations cannot both succeed
+	if err := s.Registry.Register(a); err != nil {
+		writeError(w, registryErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newResponse(a, placeholders))
+}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if s.Registry.GetByID(id) == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("agent with ID '%s' is not registered", id))
+		return
+	}
+
+	a, placeholders, err := decodeAgent(r, id)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := s.Registry.Update(a); err != nil {
+		writeError(w, registryErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newResponse(a, placeholders))
+}
+
+func (s *Server) handleDeregister(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if s.Registry.GetByID(id) == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("agent with ID '%s' is not registered", id))
+		return
+	}
+	if err := s.Registry.Unregister(id); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	if s.Health == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("heartbeats are not enabled on this server"))
+		return
+	}
+	id := r.PathValue("id")
+	if err := s.Health.Heartbeat(id); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	a := s.Registry.GetByID(id)
+	if a == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("agent with ID '%s' is not registered", id))
+		return
+	}
+
+	placeholders, err := template.Placeholders(a.GetJsonBody())
+	if err != nil {
+		placeholders = []string{}
+	}
+	writeJSON(w, http.StatusOK, newResponse(a, placeholders))
+}
+
+// newResponse builds the EnrollmentResponse for an agent.
+func newResponse(a *agent.BaseAgent, placeholders []string) EnrollmentResponse {
+	return EnrollmentResponse{
+		Agent:        a,
+		Placeholders: placeholders,
+		Availability: a.GetAvailability().String(),
+	}
+}
+
+// decodeAgent reads an EnrollmentRequest, validates it and builds the agent it describes.
+// When id is set it comes from the request path and must agree with the body.
+func decodeAgent(r *http.Request, id string) (*agent.BaseAgent, []string, error) {
+	var req EnrollmentRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return nil, nil, fmt.Errorf("invalid request body: %w", err)
+	}
+
+	if id != "" {
+		if req.ID != "" && req.ID != id {
+			return nil, nil, fmt.Errorf("body ID '%s' does not match path ID '%s'", req.ID, id)
+		}
+		req.ID = id
+	}
+
+	if req.ID == "" {
+		return nil, nil, errors.New("id is required")
+	}
+	if req.Name == "" {
+		return nil, nil, errors.New("name is required")
+	}
+	if req.Endpoint == "" {
+		return nil, nil, errors.New("endpoint is required")
+	}
+	if req.JsonBody == nil {
+		return nil, nil, errors.New("jsonBody is required")
+	}
+
+	switch req.Transport {
+	case "", agent.TransportHTTP, agent.TransportSubprocess, agent.TransportInProcess:
+	default:
+		return nil, nil, fmt.Errorf("unknown transport '%s'; expected %s, %s or %s", req.Transport, agent.TransportHTTP, agent.TransportSubprocess, agent.TransportInProcess)
+	}
+
+	placeholders, err := template.Placeholders(req.JsonBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid jsonBody template: %w", err)
+	}
+
+	a := agent.NewBaseAgent(req.ID, req.Name, req.AgentType, req.Endpoint, req.JsonBody, req.Capabilities)
+	a.Transport = req.Transport
+	return a, placeholders, nil
+}
+
+// registryErrorStatus returns the status code for an error from the registry: 409 Conflict if the agent's ID
+// or name is taken, and 500 otherwise.
+func registryErrorStatus(err error) int {
+	if errors.Is(err, agent.ErrAlreadyRegistered) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,197 @@
+package enrollment_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+	"trace/package/agent"
+	"trace/package/enrollment"
+)
+
+// doRequest sends a JSON request to the server and decodes the JSON response into out.
+func doRequest(t *testing.T, server *httptest.Server, method string, path string, body string, out interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// TestEnrollmentLifecycle registers, lists, updates and deregisters an agent.
+func TestEnrollmentLifecycle(t *testing.T) {
+	registry := agent.NewMemoryRegistry()
+	server := httptest.NewServer(enrollment.NewServer(registry, agent.NewHealthMonitor(registry, time.Minute)))
+	defer server.Close()
+
+	body := `{
+		"id": "AG200",
+		"name": "WeatherChecker",
+		"agentType": "Utility",
+		"endpoint": "https://api.weatherchecker.com",
+		"jsonBody": {"action": "get_weather", "params": {"location": "[[location]]", "date": "[[date]]"}},
+		"capabilities": ["Get Weather"]
+	}`
+
+	var created enrollment.EnrollmentResponse
+	if status := doRequest(t, server, http.MethodPost, "/agents", body, &created); status != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", status)
+	}
+	if !reflect.DeepEqual(created.Placeholders, []string{"date", "location"}) {
+		t.Errorf("Expected placeholders [date location], got %v", created.Placeholders)
+	}
+	if registry.GetByName("WeatherChecker") == nil {
+		t.Fatal("Expected agent to be registered")
+	}
+
+	if status := doRequest(t, server, http.MethodPost, "/agents", body, nil); status != http.StatusConflict {
+		t.Errorf("Expected status 409 for duplicate agent, got %d", status)
+	}
+
+	var listed []enrollment.EnrollmentResponse
+	if status := doRequest(t, server, http.MethodGet, "/agents", "", &listed); status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if len(listed) != 1 || listed[0].Agent.ID != "AG200" {
+		t.Errorf("Expected one listed agent AG200, got %+v", listed)
+	}
+
+	var beat enrollment.EnrollmentResponse
+	if status := doRequest(t, server, http.MethodPost, "/agents/AG200/heartbeat", "", &beat); status != http.StatusOK {
+		t.Fatalf("Expected status 200 for heartbeat, got %d", status)
+	}
+	if beat.Availability != agent.Available.String() {
+		t.Errorf("Expected agent to be Available after a heartbeat, got %s", beat.Availability)
+	}
+	if status := doRequest(t, server, http.MethodPost, "/agents/AG404/heartbeat", "", nil); status != http.StatusNotFound {
+		t.Errorf("Expected status 404 for heartbeat from a missing agent, got %d", status)
+	}
+
+	update := `{
+		"name": "WeatherChecker",
+		"agentType": "Utility",
+		"endpoint": "https://backup.weatherchecker.com",
+		"jsonBody": {"location": "[[location]]"}
+	}`
+	var updated enrollment.EnrollmentResponse
+	if status := doRequest(t, server, http.MethodPut, "/agents/AG200", update, &updated); status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if registry.GetByID("AG200").GetEndpoint() != "https://backup.weatherchecker.com" {
+		t.Errorf("Expected endpoint to be updated, got %s", registry.GetByID("AG200").GetEndpoint())
+	}
+	if !reflect.DeepEqual(updated.Placeholders, []string{"location"}) {
+		t.Errorf("Expected placeholders [location], got %v", updated.Placeholders)
+	}
+	if updated.Availability != agent.Available.String() {
+		t.Errorf("Expected the update to keep the agent Available, got %s", updated.Availability)
+	}
+	if registry.GetByID("AG200").LastHeartbeat().IsZero() {
+		t.Error("Expected the update to keep the agent's last heartbeat")
+	}
+
+	if status := doRequest(t, server, http.MethodDelete, "/agents/AG200", "", nil); status != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", status)
+	}
+	if registry.GetByID("AG200") != nil {
+		t.Error("Expected agent to be deregistered")
+	}
+	if status := doRequest(t, server, http.MethodDelete, "/agents/AG200", "", nil); status != http.StatusNotFound {
+		t.Errorf("Expected status 404 for missing agent, got %d", status)
+	}
+}
+
+// TestEnrollmentValidation checks that malformed enrollments are rejected.
+func TestEnrollmentValidation(t *testing.T) {
+	registry := agent.NewMemoryRegistry()
+	server := httptest.NewServer(enrollment.NewServer(registry, agent.NewHealthMonitor(registry, time.Minute)))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Invalid JSON", body: `{"id": `},
+		{name: "Missing name", body: `{"id": "AG1", "endpoint": "http://a", "jsonBody": {}}`},
+		{name: "Missing template", body: `{"id": "AG1", "name": "A", "endpoint": "http://a"}`},
+		{name: "Malformed placeholder", body: `{"id": "AG1", "name": "A", "endpoint": "http://a", "jsonBody": {"origin": "[[origin"}}`},
+		{name: "Unknown field", body: `{"id": "AG1", "name": "A", "endpoint": "http://a", "jsonBody": {}, "colour": "red"}`},
+		{name: "Unknown transport", body: `{"id": "AG1", "name": "A", "endpoint": "http://a", "transport": "grpc", "jsonBody": {}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp map[string]string
+			if status := doRequest(t, server, http.MethodPost, "/agents", tt.body, &resp); status != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", status)
+			}
+			if resp["error"] == "" {
+				t.Error("Expected an error message in the response")
+			}
+		})
+	}
+
+	if status := doRequest(t, server, http.MethodPut, "/agents/AG404", `{"name": "A", "endpoint": "http://a", "jsonBody": {}}`, nil); status != http.StatusNotFound {
+		t.Errorf("Expected status 404 updating a missing agent, got %d", status)
+	}
+}
+
+// TestHeartbeatWithoutMonitor checks that a server without a health monitor refuses heartbeats instead of failing.
+func TestHeartbeatWithoutMonitor(t *testing.T) {
+	registry := agent.NewMemoryRegistry()
+	registry.Register(agent.NewBaseAgent("AG200", "WeatherChecker", "Utility", "https://api.weatherchecker.com", map[string]interface{}{}, nil))
+	server := httptest.NewServer(enrollment.NewServer(registry, nil))
+	defer server.Close()
+
+	var resp map[string]string
+	if status := doRequest(t, server, http.MethodPost, "/agents/AG200/heartbeat", "", &resp); status != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 for a heartbeat without a monitor, got %d", status)
+	}
+	if resp["error"] == "" {
+		t.Error("Expected an error message in the response")
+	}
+}
+
+// TestConcurrentRegistration checks that only one of several concurrent registrations of an agent succeeds and
+// the rest are reported as conflicts.
+func TestConcurrentRegistration(t *testing.T) {
+	registry := agent.NewMemoryRegistry()
+	server := httptest.NewServer(enrollment.NewServer(registry, agent.NewHealthMonitor(registry, time.Minute)))
+	defer server.Close()
+
+	body := `{"id": "AG200", "name": "WeatherChecker", "endpoint": "https://api.weatherchecker.com", "jsonBody": {}}`
+	statuses := make(chan int, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(statuses); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- doRequest(t, server, http.MethodPost, "/agents", body, nil)
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := make(map[int]int)
+	for status := range statuses {
+		counts[status]++
+	}
+	if expected := map[int]int{http.StatusCreated: 1, http.StatusConflict: cap(statuses) - 1}; !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expected one 201 and the rest 409, got %v", counts)
+	}
+}
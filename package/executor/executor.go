@@ -1,8 +1,11 @@
 package executor
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 	"trace/package/agent"
 	"trace/package/logger"
@@ -11,17 +14,111 @@ import (
 	"trace/package/utils/template"
 )
 
+// Executor looks agents up in its registry and sends them task payloads through the transport registered for each agent's kind.
+type Executor struct {
+	Registry   agent.AgentRegistry
+	Transports map[string]AgentTransport
+	mu         sync.RWMutex
+}
+
+// NewExecutor creates an Executor with HTTP, subprocess and in-process transports; calls time out after the given duration.
+func NewExecutor(registry agent.AgentRegistry, timeout time.Duration) *Executor {
+	return &Executor{
+		Registry: registry,
+		Transports: map[string]AgentTransport{
+			agent.TransportHTTP:       NewHTTPTransport(timeout),
+			agent.TransportSubprocess: &SubprocessTransport{Timeout: timeout},
+			agent.TransportInProcess:  NewInProcessTransport(),
+		},
+	}
+}
+
+// NewMockExecutor creates an Executor that simulates every agent call, whatever its transport, without touching the network.
+func NewMockExecutor(registry agent.AgentRegistry) *Executor {
+	mock := &MockTransport{}
+	return &Executor{
+		Registry: registry,
+		Transports: map[string]AgentTransport{
+			agent.TransportHTTP:       mock,
+			agent.TransportSubprocess: mock,
+			agent.TransportInProcess:  mock,
+		},
+	}
+}
+
+// RegisterTransport sets the transport used for agents of the given kind.
+func (e *Executor) RegisterTransport(kind string, t AgentTransport) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.Transports == nil {
+		e.Transports = make(map[string]AgentTransport)
+	}
+	e.Transports[kind] = t
+}
+
+// RegisterAgentFunc makes fn reachable by in-process agents whose endpoint is name.
+func (e *Executor) RegisterAgentFunc(name string, fn AgentFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	inProcess, ok := e.Transports[agent.TransportInProcess].(*InProcessTransport)
+	if !ok {
+		inProcess = NewInProcessTransport()
+		if e.Transports == nil {
+			e.Transports = make(map[string]AgentTransport)
+		}
+		e.Transports[agent.TransportInProcess] = inProcess
+	}
+	inProcess.Register(name, fn)
+}
+
+// ResolveTransport returns the transport registered for the agent's kind.
+func (e *Executor) ResolveTransport(a *agent.BaseAgent) (AgentTransport, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	t, ok := e.Transports[a.GetTransport()]
+	if !ok {
+		return nil, fmt.Errorf("no transport registered for kind '%s' used by agent '%s'", a.GetTransport(), a.GetName())
+	}
+	return t, nil
+}
+
 // ExecuteTask performs the task using the provided agent and updates the task status accordingly.
-func ExecuteTask(agentName string, parserTask *parser.Task, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission, l *logger.Logger) error {
+// If ctx is done before or during the call to the agent, the task is marked Timed Out when ctx reached
+// its deadline and Cancelled otherwise.
+func (e *Executor) ExecuteTask(ctx context.Context, agentName string, parserTask *parser.Task, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission, l *logger.Logger) error {
+	_, err := e.Execute(ctx, agentName, parserTask, globalData, globalPermissions, l)
+	return err
+}
+
+// Execute performs the task like ExecuteTask and returns the task.Task that tracked it, with its final
+// status, the agent that ran it, its resolved parameters, its results and the number of attempts made.
+// The task is returned even if it failed.
+func (e *Executor) Execute(ctx context.Context, agentName string, parserTask *parser.Task, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission, l *logger.Logger) (*task.Task, error) {
     var logs []logger.Log
 
     // Convert parser.Task to task.Task
     t := ConvertParserTask(parserTask)
 
-    // Load the agent
-    a := agent.SimulateLoadAgent("Name", agentName)
-    if a == nil {
-        return fmt.Errorf("agent '%s' not found", agentName)
+    // Do not start work that is no longer wanted
+    if cause := context.Cause(ctx); cause != nil {
+        status, outcome := StoppedOutcome(cause)
+        t.UpdateStatus(status)
+        logs = append(logs, logger.NewLog(fmt.Sprintf("Task %s %s before it started: %v", t.Description, outcome, cause)))
+        logs = append(logs, logger.NewLog("Task Status: "+t.GetInfoString()))
+        l.AddLogs(logs)
+        return t, fmt.Errorf("task %s: %w", outcome, cause)
+    }
+
+    // Load the agent, falling back to a healthy agent of the same type if it is down
+    a, err := e.ResolveAgent(agentName)
+    if err != nil {
+        t.UpdateStatus(task.Failed)
+        logs = append(logs, logger.NewLog("Error loading agent for task "+t.Description+": "+err.Error()))
+        l.AddLogs(logs)
+        return t, err
+    }
+    if a.GetName() != agentName {
+        logs = append(logs, logger.NewLog(fmt.Sprintf("Agent %s is unavailable; using %s (%s) of type %s instead", agentName, a.GetName(), a.GetID(), a.GetAgentType())))
     }
 
     // Update task status and owner
@@ -29,40 +126,63 @@ func ExecuteTask(agentName string, parserTask *parser.Task, globalData map[strin
     t.UpdateOwner(a.GetID())
     logs = append(logs, logger.NewLog("Starting Task: "+t.GetInfoString()))
 
-    // Filter global data based on agent's permissions
-    filteredGlobalData := FilterGlobalDataByPermissions(a.GetName(), globalPermissions, globalData)
+    // Filter global data based on the permissions the script grants the named agent
+    filteredGlobalData := FilterGlobalDataByPermissions(agentName, globalPermissions, globalData)
 
-	// Load task parameters
-	loadedTaskParameters := template.LoadTaskParameters(t.Parameters, filteredGlobalData)
-	
-	t.UpdateParameters(loadedTaskParameters)
+    // Replace references to global data with their values; literals are passed through as written
+    resolvedParameters, err := ResolveParameters(agentName, parserTask.Parameters, globalData, filteredGlobalData)
+    if err != nil {
+        t.UpdateStatus(task.Failed)
+        logs = append(logs, logger.NewLog("Error resolving parameters: "+err.Error()))
+        logs = append(logs, logger.NewLog("Task Status: "+t.GetInfoString()))
+        l.AddLogs(logs)
+        return t, fmt.Errorf("error resolving parameters: %w", err)
+    }
+    t.UpdateParameters(resolvedParameters)
 	
     filteredDataStr, err := json.Marshal(filteredGlobalData)
     if err != nil {
         logs = append(logs, logger.NewLog("Error marshalling filtered global data: "+err.Error()))
     } else {
-        logs = append(logs, logger.NewLog("Filtered global data for agent "+a.GetName()+": "+string(filteredDataStr)))
+        logs = append(logs, logger.NewLog("Filtered global data for agent "+agentName+": "+string(filteredDataStr)))
     }
 
     // Load JSON template with parameters
     jsonPayload, err := template.LoadJSON(a.GetJsonBody(), t.Parameters, filteredGlobalData)
     if err != nil {
+        t.UpdateStatus(task.Failed)
         logs = append(logs, logger.NewLog("Error generating JSON payload: "+err.Error()))
         l.AddLogs(logs)
-        return fmt.Errorf("error generating JSON payload: %w", err)
+        return t, fmt.Errorf("error generating JSON payload: %w", err)
     }
     logs = append(logs, logger.NewLog("JSON Payload: "+jsonPayload))
 
-    // Simulate API call synchronously
-    response := SimulateAPICall(a, jsonPayload)
+    // Call the agent synchronously, retrying failed attempts as the task allows
+    response, err := e.callWithRetry(ctx, a, t, parserTask, jsonPayload, &logs)
+    if cause := context.Cause(ctx); err != nil && cause != nil {
+        status, outcome := StoppedOutcome(cause)
+        t.UpdateStatus(status)
+        logs = append(logs, logger.NewLog(fmt.Sprintf("Task %s %s while calling agent %s: %v", t.Description, outcome, a.GetName(), cause)))
+        logs = append(logs, logger.NewLog("Task Status: "+t.GetInfoString()))
+        l.AddLogs(logs)
+        return t, fmt.Errorf("task %s: %w", outcome, cause)
+    }
+    if err != nil {
+        t.UpdateStatus(task.Failed)
+        logs = append(logs, logger.NewLog("Error calling agent "+a.GetName()+": "+err.Error()))
+        logs = append(logs, logger.NewLog("Task Status: "+t.GetInfoString()))
+        l.AddLogs(logs)
+        return t, fmt.Errorf("error calling agent: %w", err)
+    }
     logs = append(logs, logger.NewLog("Response from endpoint: "+response))
 
     // Handle the response and update global data if necessary
-    err = HandleResponse(a, t, globalData, globalPermissions, response)
+    err = HandleResponse(agentName, t, globalData, globalPermissions, response)
     if err != nil {
+        t.UpdateStatus(task.Failed)
         logs = append(logs, logger.NewLog("Error handling response: "+err.Error()))
         l.AddLogs(logs)
-        return fmt.Errorf("error handling response: %w", err)
+        return t, fmt.Errorf("error handling response: %w", err)
     }
 
     // Log updated global data
@@ -76,22 +196,183 @@ func ExecuteTask(agentName string, parserTask *parser.Task, globalData map[strin
     // Add logs to the logger
     l.AddLogs(logs)
 
-    return nil
+    return t, nil
+}
+
+// ResolveAgent loads the named agent from the registry. If the agent is marked unavailable, a healthy agent
+// of the same AgentType is returned instead; if there is none, it fails fast.
+func (e *Executor) ResolveAgent(agentName string) (*agent.BaseAgent, error) {
+	if e.Registry == nil {
+		return nil, fmt.Errorf("no agent registry configured to load agent '%s'", agentName)
+	}
+	a := e.Registry.GetByName(agentName)
+	if a == nil {
+		return nil, fmt.Errorf("agent '%s' not found", agentName)
+	}
+	if a.IsAvailable() {
+		return a, nil
+	}
+
+	// Prefer alternatives known to be up over ones that have not been checked yet
+	var fallback *agent.BaseAgent
+	for _, candidate := range e.Registry.GetByType(a.GetAgentType()) {
+		if candidate.GetID() == a.GetID() || !candidate.IsAvailable() {
+			continue
+		}
+		if candidate.GetAvailability() == agent.Available {
+			return candidate, nil
+		}
+		if fallback == nil {
+			fallback = candidate
+		}
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return nil, fmt.Errorf("agent '%s' is unavailable and no healthy agent of type '%s' is registered", agentName, a.GetAgentType())
+}
+
+// StoppedOutcome returns the status and log wording for a task stopped because its context ended with cause:
+// Timed Out if a deadline passed, and Cancelled otherwise.
+func StoppedOutcome(cause error) (task.Status, string) {
+	if errors.Is(cause, context.DeadlineExceeded) {
+		return task.TimedOut, "timed out"
+	}
+	return task.Cancelled, "cancelled"
+}
+
+// CallAgent sends the payload to the agent through the transport for its kind.
+func (e *Executor) CallAgent(ctx context.Context, a *agent.BaseAgent, jsonPayload string) (string, error) {
+	t, err := e.ResolveTransport(a)
+	if err != nil {
+		return "", err
+	}
+	return t.Call(ctx, a, jsonPayload)
 }
 
+// callWithRetry calls the agent up to 1+RETRY times, bounding each attempt by the task's TIMEOUT and waiting
+// between attempts as its BACKOFF describes. Every attempt is logged with its outcome and duration.
+// Retrying stops as soon as ctx is done.
+func (e *Executor) callWithRetry(ctx context.Context, a *agent.BaseAgent, t *task.Task, parserTask *parser.Task, jsonPayload string, logs *[]logger.Log) (string, error) {
+	attempts := parserTask.Retry + 1
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			delay := RetryDelay(parserTask.Backoff, attempt-1)
+			if delay > 0 {
+				*logs = append(*logs, logger.NewLog(fmt.Sprintf("Retrying task %s in %s (%s backoff)", parserTask.TaskName, delay, parserTask.Backoff.Strategy)))
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return "", context.Cause(ctx)
+				}
+			}
+		}
+
+		start := time.Now()
+		t.RecordAttempt()
+		var response string
+		response, err = e.callWithTimeout(ctx, a, jsonPayload, parserTask.Timeout)
+		elapsed := time.Since(start)
+		if err == nil {
+			*logs = append(*logs, logger.NewLog(fmt.Sprintf("Attempt %d of %d for task %s succeeded after %s", attempt, attempts, parserTask.TaskName, elapsed)))
+			return response, nil
+		}
+		*logs = append(*logs, logger.NewLog(fmt.Sprintf("Attempt %d of %d for task %s failed after %s: %s", attempt, attempts, parserTask.TaskName, elapsed, err)))
+		if ctx.Err() != nil {
+			return "", err
+		}
+	}
+	if attempts > 1 {
+		return "", fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+	}
+	return "", err
+}
+
+// callWithTimeout calls the agent, failing if it has not answered within timeout. A zero timeout leaves the call
+// bounded only by ctx and its transport.
+func (e *Executor) callWithTimeout(ctx context.Context, a *agent.BaseAgent, jsonPayload string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return e.CallAgent(ctx, a, jsonPayload)
+	}
+
+	callCtx, cancel := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("agent '%s' did not respond within %s", a.GetName(), timeout))
+	defer cancel()
+	response, err := e.CallAgent(callCtx, a, jsonPayload)
+	if err != nil && ctx.Err() == nil && callCtx.Err() != nil {
+		return "", context.Cause(callCtx)
+	}
+	return response, err
+}
+
+// DefaultBackoffDelay is the base wait between retries when BACKOFF gives no duration.
+const DefaultBackoffDelay = time.Second
+
+// MaxBackoffDelay caps the wait before any single retry.
+const MaxBackoffDelay = 5 * time.Minute
+
+// RetryDelay returns how long to wait before the given retry, counting from 1. Fixed backoff always waits the
+// base delay, linear backoff waits retry times the base delay and exponential backoff doubles it each retry,
+// up to MaxBackoffDelay. Without a strategy, retries happen immediately.
+func RetryDelay(b parser.Backoff, retry int) time.Duration {
+	delay := b.Delay
+	if delay <= 0 {
+		delay = DefaultBackoffDelay
+	}
+	switch b.Strategy {
+	case parser.BackoffFixed:
+	case parser.BackoffLinear:
+		delay *= time.Duration(retry)
+	case parser.BackoffExponential:
+		for i := 1; i < retry && delay < MaxBackoffDelay; i++ {
+			delay *= 2
+		}
+	default:
+		return 0
+	}
+	if delay > MaxBackoffDelay {
+		return MaxBackoffDelay
+	}
+	return delay
+}
 
 // ConvertParserTask converts a parser.Task to a task.Task.
 func ConvertParserTask(parserTask *parser.Task) *task.Task {
-	// Convert Parameters from map[string]string to map[string]interface{}
+	// Convert Parameters from map[string]parser.Parameter to map[string]interface{}
 	parameters := make(map[string]interface{})
-	for key, value := range parserTask.Parameters {
-		parameters[key] = value
+	for key, param := range parserTask.Parameters {
+		parameters[key] = param.Value
 	}
 
 	// Create a new task.Task using task.CreateTask
 	return task.CreateTask(parserTask.TaskName, parameters)
 }
 
+// ResolveParameters returns the task's parameter values with each reference to global data replaced by
+// the value readable by the agent. Literals are never dereferenced, and OUTPUT keeps the name it refers to.
+// A reference the agent cannot read is an error.
+func ResolveParameters(agentName string, parameters map[string]parser.Parameter, globalData map[string]*parser.Data, readableData map[string]interface{}) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{})
+	for key, param := range parameters {
+		if key == "OUTPUT" || !param.IsReference() {
+			resolved[key] = param.Value
+			continue
+		}
+
+		value, readable := readableData[param.Value]
+		if !readable {
+			if _, declared := globalData[param.Value]; !declared {
+				return nil, fmt.Errorf("parameter '%s' refers to undeclared data '%s'", key, param.Value)
+			}
+			return nil, fmt.Errorf("parameter '%s' refers to '%s', but agent '%s' does not have READ permission for it", key, param.Value, agentName)
+		}
+		resolved[key] = value
+	}
+	return resolved, nil
+}
+
 // GlobalDataToString converts the global data map to a JSON string for logging.
 func GlobalDataToString(globalData map[string]*parser.Data) string {
 	dataCopy := make(map[string]interface{})
@@ -114,8 +395,8 @@ func GlobalDataToString(globalData map[string]*parser.Data) string {
 }
 
 // GetAgentPermissions retrieves the data permissions for a specific agent.
-func GetAgentPermissions(a *agent.BaseAgent, globalPermissions map[string]*parser.Permission) map[string][]string {
-	agentPermissions, ok := globalPermissions[a.GetName()]
+func GetAgentPermissions(agentName string, globalPermissions map[string]*parser.Permission) map[string][]string {
+	agentPermissions, ok := globalPermissions[agentName]
 	if !ok {
 		return nil
 	}
@@ -124,12 +405,7 @@ func GetAgentPermissions(a *agent.BaseAgent, globalPermissions map[string]*parse
 
 // FilterGlobalDataByPermissions filters global data based on agent's permissions.
 func FilterGlobalDataByPermissions(agentName string, globalPermissions map[string]*parser.Permission, globalData map[string]*parser.Data) map[string]interface{} {
-	agent := agent.SimulateLoadAgent("Name", agentName)
-	if agent == nil {
-		return nil
-	}
-
-	agentPermissions := GetAgentPermissions(agent, globalPermissions)
+	agentPermissions := GetAgentPermissions(agentName, globalPermissions)
 	if agentPermissions == nil {
 		return nil
 	}
@@ -159,8 +435,8 @@ func HasPermission(permissions []string, target string) bool {
 	return false
 }
 
-// HandleResponse updates the global data if required.
-func HandleResponse(a *agent.BaseAgent, t *task.Task, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission, response string) error {
+// HandleResponse updates the global data if required, using the permissions granted to the named agent.
+func HandleResponse(agentName string, t *task.Task, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission, response string) error {
 	variableRaw, hasOutputParameter := t.Parameters["OUTPUT"]
 	if !hasOutputParameter {
 		t.UpdateResult(response)
@@ -172,14 +448,14 @@ func HandleResponse(a *agent.BaseAgent, t *task.Task, globalData map[string]*par
 		return fmt.Errorf("expected OUTPUT parameter to be a string, got %T", variableRaw)
 	}
 
-	agentPermissions := GetAgentPermissions(a, globalPermissions)
+	agentPermissions := GetAgentPermissions(agentName, globalPermissions)
 	if agentPermissions == nil {
-		return fmt.Errorf("agent '%s' does not have any permissions defined", a.GetName())
+		return fmt.Errorf("agent '%s' does not have any permissions defined", agentName)
 	}
 
 	permissions, variableExists := agentPermissions[variable]
 	if !variableExists || !HasPermission(permissions, "WRITE") {
-		return fmt.Errorf("agent '%s' does not have WRITE permission for variable '%s'", a.GetName(), variable)
+		return fmt.Errorf("agent '%s' does not have WRITE permission for variable '%s'", agentName, variable)
 	}
 
 	data, found := globalData[variable]
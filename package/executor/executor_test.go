@@ -1,8 +1,16 @@
 package executor_test
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 	"trace/package/agent"
 	"trace/package/executor"
 	"trace/package/logger"
@@ -12,7 +20,8 @@ import (
 // TestExecuteTask_Success verifies the successful execution of a task.
 func TestExecuteTask_Success(t *testing.T) {
 	// Load a mock agent
-	mockAgent := agent.SimulateLoadAgent("Name", "Flight Getter")
+	registry := agent.NewMockRegistry()
+	mockAgent := registry.GetByName("FlightGetter")
 	if mockAgent == nil {
 		t.Fatal("Agent not found")
 	}
@@ -20,12 +29,12 @@ func TestExecuteTask_Success(t *testing.T) {
 	// Create a parser task with valid parameters
 	mockTask := &parser.Task{
 		TaskName:  "Book Flight",
-		AgentName: "Flight Getter",
-		Parameters: map[string]string{
-			"origin":      "NYC",
-			"destination": "LAX",
-			"date":        "2023-10-10",
-			"OUTPUT":      "flightInfo",
+		AgentName: "FlightGetter",
+		Parameters: map[string]parser.Parameter{
+			"origin":      {Value: "NYC", Type: parser.STRING},
+			"destination": {Value: "LAX", Type: parser.STRING},
+			"date":        {Value: "2023-10-10", Type: parser.STRING},
+			"OUTPUT":      {Value: "flightInfo", Type: parser.IDENT},
 		},
 	}
 
@@ -41,8 +50,8 @@ func TestExecuteTask_Success(t *testing.T) {
 
 	// Define global permissions for the mock agent
 	globalPermissions := map[string]*parser.Permission{
-		"Flight Getter": {
-			AgentName: "Flight Getter",
+		"FlightGetter": {
+			AgentName: "FlightGetter",
 			DataPermissions: map[string][]string{
 				"flightInfo": {"READ", "WRITE"},
 			},
@@ -53,7 +62,7 @@ func TestExecuteTask_Success(t *testing.T) {
 	log := logger.NewLogger()
 
 	// Execute the task
-	err := executor.ExecuteTask(mockAgent.GetName(), mockTask, globalData, globalPermissions, log)
+	err := executor.NewMockExecutor(registry).ExecuteTask(context.Background(), mockAgent.GetName(), mockTask, globalData, globalPermissions, log)
 	if err != nil {
 		t.Fatalf("ExecuteTask failed: %v", err)
 	}
@@ -72,7 +81,8 @@ func TestExecuteTask_Success(t *testing.T) {
 // TestExecuteTask_NoWritePermission verifies behavior when the agent lacks WRITE permission.
 func TestExecuteTask_NoWritePermission(t *testing.T) {
 	// Load a mock agent
-	mockAgent := agent.SimulateLoadAgent("Name", "Flight Getter")
+	registry := agent.NewMockRegistry()
+	mockAgent := registry.GetByName("FlightGetter")
 	if mockAgent == nil {
 		t.Fatal("Agent not found")
 	}
@@ -80,12 +90,12 @@ func TestExecuteTask_NoWritePermission(t *testing.T) {
 	// Create a parser task
 	mockTask := &parser.Task{
 		TaskName:  "Book Flight",
-		AgentName: "Flight Getter",
-		Parameters: map[string]string{
-			"origin":      "NYC",
-			"destination": "LAX",
-			"date":        "2023-10-10",
-			"OUTPUT":      "flightInfo",
+		AgentName: "FlightGetter",
+		Parameters: map[string]parser.Parameter{
+			"origin":      {Value: "NYC", Type: parser.STRING},
+			"destination": {Value: "LAX", Type: parser.STRING},
+			"date":        {Value: "2023-10-10", Type: parser.STRING},
+			"OUTPUT":      {Value: "flightInfo", Type: parser.IDENT},
 		},
 	}
 
@@ -101,8 +111,8 @@ func TestExecuteTask_NoWritePermission(t *testing.T) {
 
 	// Define global permissions without WRITE permission
 	globalPermissions := map[string]*parser.Permission{
-		"Flight Getter": {
-			AgentName: "Flight Getter",
+		"FlightGetter": {
+			AgentName: "FlightGetter",
 			DataPermissions: map[string][]string{
 				"flightInfo": {"READ"}, // Lacks WRITE permission
 			},
@@ -113,7 +123,7 @@ func TestExecuteTask_NoWritePermission(t *testing.T) {
 	log := logger.NewLogger()
 
 	// Execute the task
-	err := executor.ExecuteTask(mockAgent.GetName(), mockTask, globalData, globalPermissions, log)
+	err := executor.NewMockExecutor(registry).ExecuteTask(context.Background(), mockAgent.GetName(), mockTask, globalData, globalPermissions, log)
 	if err == nil {
 		t.Fatal("Expected error due to lack of WRITE permission, but got none")
 	}
@@ -125,7 +135,8 @@ func TestExecuteTask_NoWritePermission(t *testing.T) {
 // TestExecuteTask_MissingGlobalData verifies behavior when required global data is missing.
 func TestExecuteTask_MissingGlobalData(t *testing.T) {
 	// Load a mock agent
-	mockAgent := agent.SimulateLoadAgent("Name", "Flight Getter")
+	registry := agent.NewMockRegistry()
+	mockAgent := registry.GetByName("FlightGetter")
 	if mockAgent == nil {
 		t.Fatal("Agent not found")
 	}
@@ -133,12 +144,12 @@ func TestExecuteTask_MissingGlobalData(t *testing.T) {
 	// Create a parser task
 	mockTask := &parser.Task{
 		TaskName:  "Book Flight",
-		AgentName: "Flight Getter",
-		Parameters: map[string]string{
-			"origin":      "NYC",
-			"destination": "LAX",
-			"date":        "2023-10-10",
-			"OUTPUT":      "flightInfo",
+		AgentName: "FlightGetter",
+		Parameters: map[string]parser.Parameter{
+			"origin":      {Value: "NYC", Type: parser.STRING},
+			"destination": {Value: "LAX", Type: parser.STRING},
+			"date":        {Value: "2023-10-10", Type: parser.STRING},
+			"OUTPUT":      {Value: "flightInfo", Type: parser.IDENT},
 		},
 	}
 
@@ -147,8 +158,8 @@ func TestExecuteTask_MissingGlobalData(t *testing.T) {
 
 	// Define global permissions
 	globalPermissions := map[string]*parser.Permission{
-		"Flight Getter": {
-			AgentName: "Flight Getter",
+		"FlightGetter": {
+			AgentName: "FlightGetter",
 			DataPermissions: map[string][]string{
 				"flightInfo": {"READ", "WRITE"},
 			},
@@ -159,7 +170,7 @@ func TestExecuteTask_MissingGlobalData(t *testing.T) {
 	log := logger.NewLogger()
 
 	// Execute the task
-	err := executor.ExecuteTask(mockAgent.GetName(), mockTask, globalData, globalPermissions, log)
+	err := executor.NewMockExecutor(registry).ExecuteTask(context.Background(), mockAgent.GetName(), mockTask, globalData, globalPermissions, log)
 	if err == nil {
 		t.Fatal("Expected error due to missing global data, but got none")
 	}
@@ -167,3 +178,327 @@ func TestExecuteTask_MissingGlobalData(t *testing.T) {
 	// Print logs for debugging purposes
 	log.PrintAllLogs()
 }
+
+// TestSendHTTPRequest_Success verifies that the payload is POSTed and the response body is returned.
+func TestSendHTTPRequest_Success(t *testing.T) {
+	var receivedBody, receivedContentType, receivedMethod string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receivedBody = string(body)
+		receivedContentType = r.Header.Get("Content-Type")
+		receivedMethod = r.Method
+		w.Write([]byte(`{"flight":"UA123"}`))
+	}))
+	defer server.Close()
+
+	a := agent.NewBaseAgent("AG900", "FlightGetter", "Travel", server.URL, nil, nil)
+	e := executor.NewExecutor(agent.NewMemoryRegistry(), time.Second)
+
+	response, err := e.CallAgent(context.Background(), a, `{"origin":"NYC"}`)
+	if err != nil {
+		t.Fatalf("CallAgent failed: %v", err)
+	}
+
+	if receivedMethod != http.MethodPost {
+		t.Errorf("Expected method POST, got %s", receivedMethod)
+	}
+	if receivedContentType != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %s", receivedContentType)
+	}
+	if receivedBody != `{"origin":"NYC"}` {
+		t.Errorf("Expected payload to be forwarded, got %s", receivedBody)
+	}
+	if response != `{"flight":"UA123"}` {
+		t.Errorf("Expected response body to be captured, got %s", response)
+	}
+}
+
+// TestSendHTTPRequest_ErrorStatus verifies that a non-2xx response becomes an HTTPStatusError.
+func TestSendHTTPRequest_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no seats left", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	a := agent.NewBaseAgent("AG900", "FlightGetter", "Travel", server.URL, nil, nil)
+	e := executor.NewExecutor(agent.NewMemoryRegistry(), time.Second)
+
+	_, err := e.CallAgent(context.Background(), a, `{}`)
+	if err == nil {
+		t.Fatal("Expected error for 503 response, but got none")
+	}
+
+	var statusErr *executor.HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Expected HTTPStatusError, got %T: %v", err, err)
+	}
+	if statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code 503, got %d", statusErr.StatusCode)
+	}
+	if !strings.Contains(statusErr.Body, "no seats left") {
+		t.Errorf("Expected body to be captured, got %q", statusErr.Body)
+	}
+}
+
+// TestSendHTTPRequest_Timeout verifies that slow agents fail once the executor's timeout passes.
+func TestSendHTTPRequest_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	a := agent.NewBaseAgent("AG900", "FlightGetter", "Travel", server.URL, nil, nil)
+	e := executor.NewExecutor(agent.NewMemoryRegistry(), 50*time.Millisecond)
+
+	if _, err := e.CallAgent(context.Background(), a, `{}`); err == nil {
+		t.Fatal("Expected timeout error, but got none")
+	}
+}
+
+// TestExecuteTask_HTTPAgent verifies a registered HTTP agent receives the payload and its response is written to OUTPUT.
+func TestExecuteTask_HTTPAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"origin":"Chicago"`) {
+			t.Errorf("Expected payload to contain origin, got %s", body)
+		}
+		w.Write([]byte(`{"flight":"UA123"}`))
+	}))
+	defer server.Close()
+
+	registry := agent.NewMemoryRegistry()
+	registry.Register(agent.NewBaseAgent("AG900", "FlightGetter", "Travel", server.URL,
+		map[string]interface{}{"origin": "[[origin]]"}, nil))
+
+	mockTask := &parser.Task{
+		TaskName:   "Book Flight",
+		AgentName:  "FlightGetter",
+		Parameters: map[string]parser.Parameter{"origin": {Value: "origin", Type: parser.IDENT}, "OUTPUT": {Value: "flightInfo", Type: parser.IDENT}},
+	}
+	globalData := map[string]*parser.Data{
+		"origin":     {DataName: "origin", DataType: "String", InitialValue: "Chicago"},
+		"flightInfo": {DataName: "flightInfo", DataType: "String"},
+	}
+	globalPermissions := map[string]*parser.Permission{
+		"FlightGetter": {
+			AgentName: "FlightGetter",
+			DataPermissions: map[string][]string{
+				"origin":     {"READ"},
+				"flightInfo": {"WRITE"},
+			},
+		},
+	}
+
+	err := executor.NewExecutor(registry, time.Second).ExecuteTask(context.Background(), "FlightGetter", mockTask, globalData, globalPermissions, logger.NewLogger())
+	if err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+	if globalData["flightInfo"].InitialValue != `{"flight":"UA123"}` {
+		t.Errorf("Expected flightInfo to hold the response, got %q", globalData["flightInfo"].InitialValue)
+	}
+}
+
+// TestExecuteTask_HTTPErrorLogged verifies a non-2xx response fails the task and is recorded in the logger.
+func TestExecuteTask_HTTPErrorLogged(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream exploded", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	registry := agent.NewMemoryRegistry()
+	registry.Register(agent.NewBaseAgent("AG900", "FlightGetter", "Travel", server.URL, map[string]interface{}{}, nil))
+
+	mockTask := &parser.Task{
+		TaskName:   "Book Flight",
+		AgentName:  "FlightGetter",
+		Parameters: map[string]parser.Parameter{},
+	}
+
+	log := logger.NewLogger()
+	err := executor.NewExecutor(registry, time.Second).ExecuteTask(context.Background(), "FlightGetter", mockTask, map[string]*parser.Data{}, map[string]*parser.Permission{}, log)
+	if err == nil {
+		t.Fatal("Expected error for 500 response, but got none")
+	}
+
+	found := false
+	for _, entry := range log.GetAllLogs() {
+		if strings.Contains(entry.Information(), "status 500") && strings.Contains(entry.Information(), "upstream exploded") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the failed call to be recorded in the logger")
+	}
+}
+
+// TestExecuteTask_UnknownAgent verifies agents missing from the registry are rejected.
+func TestExecuteTask_UnknownAgent(t *testing.T) {
+	mockTask := &parser.Task{TaskName: "Ghost", AgentName: "Nobody", Parameters: map[string]parser.Parameter{}}
+
+	err := executor.NewMockExecutor(agent.NewMockRegistry()).ExecuteTask(context.Background(), "Nobody", mockTask, map[string]*parser.Data{}, map[string]*parser.Permission{}, logger.NewLogger())
+	if err == nil {
+		t.Fatal("Expected error for unregistered agent, but got none")
+	}
+}
+
+// TestResolveAgent_Availability verifies unavailable agents are replaced by a healthy agent of the same type, or fail fast.
+func TestResolveAgent_Availability(t *testing.T) {
+	registry := agent.NewMemoryRegistry()
+	primary := agent.NewBaseAgent("AG1", "WeatherA", "Utility", "http://a", nil, nil)
+	backup := agent.NewBaseAgent("AG2", "WeatherB", "Utility", "http://b", nil, nil)
+	other := agent.NewBaseAgent("AG3", "Flights", "Travel", "http://c", nil, nil)
+	for _, a := range []*agent.BaseAgent{primary, backup, other} {
+		registry.Register(a)
+	}
+	e := executor.NewMockExecutor(registry)
+
+	if a, err := e.ResolveAgent("WeatherA"); err != nil || a != primary {
+		t.Errorf("Expected the named agent while it is healthy, got %v, %v", a, err)
+	}
+
+	primary.SetAvailability(agent.Unavailable)
+	backup.SetAvailability(agent.Available)
+	if a, err := e.ResolveAgent("WeatherA"); err != nil || a != backup {
+		t.Errorf("Expected fallback to WeatherB, got %v, %v", a, err)
+	}
+
+	backup.SetAvailability(agent.Unavailable)
+	if _, err := e.ResolveAgent("WeatherA"); err == nil {
+		t.Error("Expected error when no healthy agent of the same type exists, but got none")
+	}
+
+	other.SetAvailability(agent.Unavailable)
+	if _, err := e.ResolveAgent("Flights"); err == nil {
+		t.Error("Expected error for unavailable agent without alternatives, but got none")
+	}
+}
+
+// TestExecuteTask_FallbackKeepsPermissions verifies a substitute agent acts with the permissions granted to the named agent.
+func TestExecuteTask_FallbackKeepsPermissions(t *testing.T) {
+	registry := agent.NewMemoryRegistry()
+	primary := agent.NewBaseAgent("AG1", "WeatherA", "Utility", "http://a", map[string]interface{}{}, nil)
+	backup := agent.NewBaseAgent("AG2", "WeatherB", "Utility", "http://b", map[string]interface{}{}, nil)
+	registry.Register(primary)
+	registry.Register(backup)
+	primary.SetAvailability(agent.Unavailable)
+
+	mockTask := &parser.Task{
+		TaskName:   "CheckWeather",
+		AgentName:  "WeatherA",
+		Parameters: map[string]parser.Parameter{"OUTPUT": {Value: "weatherInfo", Type: parser.IDENT}},
+	}
+	globalData := map[string]*parser.Data{
+		"weatherInfo": {DataName: "weatherInfo", DataType: "String"},
+	}
+	globalPermissions := map[string]*parser.Permission{
+		"WeatherA": {AgentName: "WeatherA", DataPermissions: map[string][]string{"weatherInfo": {"WRITE"}}},
+	}
+
+	e := executor.NewExecutor(registry, time.Second)
+	e.RegisterTransport(agent.TransportHTTP, &executor.MockTransport{})
+
+	log := logger.NewLogger()
+	if err := e.ExecuteTask(context.Background(), "WeatherA", mockTask, globalData, globalPermissions, log); err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+	if globalData["weatherInfo"].InitialValue != "simulated response" {
+		t.Errorf("Expected weatherInfo to be written, got %q", globalData["weatherInfo"].InitialValue)
+	}
+
+	found := false
+	for _, entry := range log.GetAllLogs() {
+		if strings.Contains(entry.Information(), "using WeatherB") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the substitution to be logged")
+	}
+}
+
+// TestResolveParameters verifies that only references are replaced with global data.
+func TestResolveParameters(t *testing.T) {
+	globalData := map[string]*parser.Data{
+		"origin":     {DataName: "origin", InitialValue: "Chicago"},
+		"secret":     {DataName: "secret", InitialValue: "hidden"},
+		"flightInfo": {DataName: "flightInfo"},
+	}
+	globalPermissions := map[string]*parser.Permission{
+		"FlightGetter": {
+			AgentName: "FlightGetter",
+			DataPermissions: map[string][]string{
+				"origin":     {"READ"},
+				"flightInfo": {"WRITE"},
+			},
+		},
+	}
+	readable := executor.FilterGlobalDataByPermissions("FlightGetter", globalPermissions, globalData)
+
+	resolved, err := executor.ResolveParameters("FlightGetter", map[string]parser.Parameter{
+		"from":   {Value: "origin", Type: parser.IDENT},
+		"label":  {Value: "origin", Type: parser.STRING},
+		"count":  {Value: "2", Type: parser.NUMBER},
+		"OUTPUT": {Value: "flightInfo", Type: parser.IDENT},
+	}, globalData, readable)
+	if err != nil {
+		t.Fatalf("ResolveParameters failed: %v", err)
+	}
+	expected := map[string]interface{}{"from": "Chicago", "label": "origin", "count": "2", "OUTPUT": "flightInfo"}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("Expected %v, got %v", expected, resolved)
+	}
+
+	tests := []struct {
+		name     string
+		param    parser.Parameter
+		expected string
+	}{
+		{"Unreadable reference", parser.Parameter{Value: "secret", Type: parser.IDENT}, "agent 'FlightGetter' does not have READ permission"},
+		{"Undeclared reference", parser.Parameter{Value: "missing", Type: parser.IDENT}, "undeclared data 'missing'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executor.ResolveParameters("FlightGetter", map[string]parser.Parameter{"input": tt.param}, globalData, readable)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+// TestExecuteTask_UnreadableReference verifies that the agent is not called when a reference cannot be read.
+func TestExecuteTask_UnreadableReference(t *testing.T) {
+	registry := agent.NewMockRegistry()
+	mockTask := &parser.Task{
+		TaskName:   "Book Flight",
+		AgentName:  "FlightGetter",
+		Parameters: map[string]parser.Parameter{"origin": {Value: "origin", Type: parser.IDENT}},
+	}
+	globalData := map[string]*parser.Data{"origin": {DataName: "origin", InitialValue: "Chicago"}}
+	globalPermissions := map[string]*parser.Permission{}
+
+	e := executor.NewMockExecutor(registry)
+	transport := &countingTransport{}
+	e.RegisterTransport(agent.TransportHTTP, transport)
+
+	log := logger.NewLogger()
+	if err := e.ExecuteTask(context.Background(), "FlightGetter", mockTask, globalData, globalPermissions, log); err == nil {
+		t.Fatal("Expected error for an unreadable reference, but got none")
+	}
+	if transport.calls != 0 {
+		t.Errorf("Expected the agent not to be called, got %d calls", transport.calls)
+	}
+}
+
+// countingTransport records how many times it is called.
+type countingTransport struct {
+	calls int
+}
+
+func (c *countingTransport) Call(ctx context.Context, a *agent.BaseAgent, jsonPayload string) (string, error) {
+	c.calls++
+	return "{}", nil
+}
@@ -0,0 +1,236 @@
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"trace/package/agent"
+	"trace/package/executor"
+	"trace/package/logger"
+	"trace/package/parser"
+	"trace/package/task"
+)
+
+// agentHandler answers a call to the named in-process agent.
+type agentHandler func(agentName string, jsonPayload string) (string, error)
+
+// newInProcessExecutor returns an executor whose agents are in-process functions that pass every call to
+// handle. Each agent fills in the given JSON template.
+func newInProcessExecutor(t *testing.T, jsonBody map[string]interface{}, handle agentHandler, agentNames ...string) *executor.Executor {
+	t.Helper()
+	registry := agent.NewMemoryRegistry()
+	e := executor.NewExecutor(registry, time.Second)
+	for i, name := range agentNames {
+		a := agent.NewBaseAgent("AG"+string(rune('A'+i)), name, "Test", name, jsonBody, nil)
+		a.Transport = agent.TransportInProcess
+		if err := registry.Register(a); err != nil {
+			t.Fatalf("Register(%s) failed: %v", name, err)
+		}
+		agentName := name
+		e.RegisterAgentFunc(name, func(jsonPayload string) (string, error) {
+			return handle(agentName, jsonPayload)
+		})
+	}
+	return e
+}
+
+// failingTimes returns a handler that fails the first n calls and counts every call.
+func failingTimes(n int, calls *int) agentHandler {
+	var mu sync.Mutex
+	return func(agentName string, jsonPayload string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		*calls++
+		if *calls <= n {
+			return "", errors.New("connection reset")
+		}
+		return "ok", nil
+	}
+}
+
+// TestExecuteTask_Retry verifies that failed attempts are retried with backoff and logged one by one.
+func TestExecuteTask_Retry(t *testing.T) {
+	calls := 0
+	e := newInProcessExecutor(t, map[string]interface{}{}, failingTimes(2, &calls), "Flaky")
+	mockTask := &parser.Task{
+		TaskName:   "Ping",
+		AgentName:  "Flaky",
+		Parameters: map[string]parser.Parameter{},
+		Retry:      3,
+		Backoff:    parser.Backoff{Strategy: parser.BackoffFixed, Delay: 10 * time.Millisecond},
+	}
+
+	log := logger.NewLogger()
+	start := time.Now()
+	if err := e.ExecuteTask(context.Background(), "Flaky", mockTask, map[string]*parser.Data{}, map[string]*parser.Permission{}, log); err != nil {
+		t.Fatalf("Expected the task to succeed on its third attempt, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected two 10ms backoffs, but the task finished in %s", elapsed)
+	}
+
+	text := log.Text()
+	for _, expected := range []string{
+		"Attempt 1 of 4 for task Ping failed after",
+		": connection reset",
+		"Retrying task Ping in 10ms (fixed backoff)",
+		"Attempt 2 of 4 for task Ping failed after",
+		"Attempt 3 of 4 for task Ping succeeded after",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected log containing %q, got:\n%s", expected, text)
+		}
+	}
+	if strings.Contains(text, "Attempt 4 of 4") {
+		t.Errorf("Expected no fourth attempt, got:\n%s", text)
+	}
+}
+
+// TestExecuteTask_RetryExhausted verifies that the task fails once every attempt has failed.
+func TestExecuteTask_RetryExhausted(t *testing.T) {
+	calls := 0
+	e := newInProcessExecutor(t, map[string]interface{}{}, failingTimes(10, &calls), "Flaky")
+	mockTask := &parser.Task{TaskName: "Ping", AgentName: "Flaky", Parameters: map[string]parser.Parameter{}, Retry: 2}
+
+	err := e.ExecuteTask(context.Background(), "Flaky", mockTask, map[string]*parser.Data{}, map[string]*parser.Permission{}, logger.NewLogger())
+	if err == nil {
+		t.Fatal("Expected an error once every attempt failed, but got none")
+	}
+	if !strings.Contains(err.Error(), "giving up after 3 attempts: connection reset") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+// TestExecute_Attempts verifies that the tracked task reports its status and how many attempts it took.
+func TestExecute_Attempts(t *testing.T) {
+	calls := 0
+	e := newInProcessExecutor(t, map[string]interface{}{}, failingTimes(1, &calls), "Flaky")
+	mockTask := &parser.Task{TaskName: "Ping", AgentName: "Flaky", Parameters: map[string]parser.Parameter{}, Retry: 2}
+
+	tracked, err := e.Execute(context.Background(), "Flaky", mockTask, map[string]*parser.Data{}, map[string]*parser.Permission{}, logger.NewLogger())
+	if err != nil {
+		t.Fatalf("Expected the task to succeed on its second attempt, got %v", err)
+	}
+	if tracked.Attempts != 2 || tracked.Status != task.Finished || tracked.Owner != "AGA" {
+		t.Errorf("Expected Finished by AGA after 2 attempts, got %s by %s after %d", tracked.Status, tracked.Owner, tracked.Attempts)
+	}
+	if len(tracked.Result) != 1 || tracked.Result[0] != "ok" {
+		t.Errorf("Expected the response as the result, got %v", tracked.Result)
+	}
+}
+
+// TestExecuteTask_Timeout verifies that an attempt taking longer than TIMEOUT fails and is retried.
+func TestExecuteTask_Timeout(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	e := newInProcessExecutor(t, map[string]interface{}{}, func(agentName string, jsonPayload string) (string, error) {
+		mu.Lock()
+		calls++
+		slow := calls == 1
+		mu.Unlock()
+		if slow {
+			time.Sleep(500 * time.Millisecond)
+		}
+		return "ok", nil
+	}, "Flaky")
+	mockTask := &parser.Task{TaskName: "Ping", AgentName: "Flaky", Parameters: map[string]parser.Parameter{}, Retry: 1, Timeout: 20 * time.Millisecond}
+
+	log := logger.NewLogger()
+	start := time.Now()
+	if err := e.ExecuteTask(context.Background(), "Flaky", mockTask, map[string]*parser.Data{}, map[string]*parser.Permission{}, log); err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("Expected the slow attempt to be abandoned, but the task took %s", elapsed)
+	}
+	if text := log.Text(); !strings.Contains(text, "agent 'Flaky' did not respond within 20ms") {
+		t.Errorf("Expected a timeout in the logs, got:\n%s", text)
+	}
+}
+
+// TestRetryDelay verifies the wait before each retry for every backoff strategy.
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		backoff  parser.Backoff
+		retry    int
+		expected time.Duration
+	}{
+		{parser.Backoff{}, 3, 0},
+		{parser.Backoff{Strategy: parser.BackoffFixed, Delay: 200 * time.Millisecond}, 3, 200 * time.Millisecond},
+		{parser.Backoff{Strategy: parser.BackoffLinear, Delay: 200 * time.Millisecond}, 3, 600 * time.Millisecond},
+		{parser.Backoff{Strategy: parser.BackoffExponential, Delay: 200 * time.Millisecond}, 1, 200 * time.Millisecond},
+		{parser.Backoff{Strategy: parser.BackoffExponential, Delay: 200 * time.Millisecond}, 4, 1600 * time.Millisecond},
+		{parser.Backoff{Strategy: parser.BackoffExponential}, 2, 2 * executor.DefaultBackoffDelay},
+		{parser.Backoff{Strategy: parser.BackoffExponential}, 100, executor.MaxBackoffDelay},
+	}
+
+	for _, tt := range tests {
+		if got := executor.RetryDelay(tt.backoff, tt.retry); got != tt.expected {
+			t.Errorf("RetryDelay(%+v, %d): expected %s, got %s", tt.backoff, tt.retry, tt.expected, got)
+		}
+	}
+}
+
+// TestExecuteTask_Cancelled verifies that cancelling the context abandons the call, stops retrying and marks the task Cancelled.
+func TestExecuteTask_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	e := newInProcessExecutor(t, map[string]interface{}{}, func(agentName string, jsonPayload string) (string, error) {
+		<-release
+		return "too late", nil
+	}, "Flaky")
+	mockTask := &parser.Task{
+		TaskName:   "Ping",
+		AgentName:  "Flaky",
+		Parameters: map[string]parser.Parameter{},
+		Retry:      3,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	log := logger.NewLogger()
+	start := time.Now()
+	err := e.ExecuteTask(ctx, "Flaky", mockTask, map[string]*parser.Data{}, map[string]*parser.Permission{}, log)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancellation error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the task to stop as soon as it was cancelled, but it took %s", elapsed)
+	}
+
+	text := log.Text()
+	for _, expected := range []string{
+		"Task Ping cancelled while calling agent Flaky: context canceled",
+		"Status: Cancelled",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, text)
+		}
+	}
+	if strings.Contains(text, "Attempt 2 of 4") {
+		t.Errorf("Expected no retries after cancellation, got:\n%s", text)
+	}
+
+	calls := 0
+	e = newInProcessExecutor(t, map[string]interface{}{}, failingTimes(0, &calls), "Flaky")
+	log = logger.NewLogger()
+	if err := e.ExecuteTask(ctx, "Flaky", mockTask, map[string]*parser.Data{}, map[string]*parser.Permission{}, log); err == nil {
+		t.Fatal("Expected an error for a task started after cancellation")
+	}
+	if calls != 0 {
+		t.Errorf("Expected the agent not to be called, got %d calls", calls)
+	}
+	if text := log.Text(); !strings.Contains(text, "Task Ping cancelled before it started: context canceled") {
+		t.Errorf("Expected the task to be logged as cancelled, got:\n%s", text)
+	}
+}
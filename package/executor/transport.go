@@ -0,0 +1,200 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	"trace/package/agent"
+)
+
+// DefaultTimeout bounds a single call to an agent when no timeout is given.
+const DefaultTimeout = 30 * time.Second
+
+// maxResponseBytes caps how much of an agent's response body is captured.
+const maxResponseBytes = 10 << 20
+
+// AgentTransport delivers a JSON payload to an agent and returns the agent's response.
+// A call must give up as soon as ctx is done.
+type AgentTransport interface {
+	Call(ctx context.Context, a *agent.BaseAgent, jsonPayload string) (string, error)
+}
+
+// HTTPStatusError is returned when an agent answers with a non-2xx status code.
+type HTTPStatusError struct {
+	AgentName  string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("agent '%s' returned status %d: %s", e.AgentName, e.StatusCode, e.Body)
+}
+
+// HTTPTransport POSTs payloads to the agent's endpoint URL.
+type HTTPTransport struct {
+	Client *http.Client
+}
+
+// NewHTTPTransport creates an HTTPTransport whose requests time out after the given duration.
+func NewHTTPTransport(timeout time.Duration) *HTTPTransport {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &HTTPTransport{
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Call POSTs the payload to the agent's endpoint and returns the response body.
+func (h *HTTPTransport) Call(ctx context.Context, a *agent.BaseAgent, jsonPayload string) (string, error) {
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.GetEndpoint(), strings.NewReader(jsonPayload))
+	if err != nil {
+		return "", fmt.Errorf("error creating request for agent '%s': %w", a.GetName(), err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending request to agent '%s': %w", a.GetName(), err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return "", fmt.Errorf("error reading response from agent '%s': %w", a.GetName(), err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", &HTTPStatusError{
+			AgentName:  a.GetName(),
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+		}
+	}
+
+	return string(body), nil
+}
+
+// SubprocessTransport runs the agent's endpoint as a command line, writes the payload to its stdin
+// and reads a JSON response from its stdout.
+type SubprocessTransport struct {
+	Timeout time.Duration
+}
+
+// Call runs the agent's command and returns what it printed to stdout.
+func (s *SubprocessTransport) Call(ctx context.Context, a *agent.BaseAgent, jsonPayload string) (string, error) {
+	args := strings.Fields(a.GetEndpoint())
+	if len(args) == 0 {
+		return "", fmt.Errorf("agent '%s' has no command to run", a.GetName())
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(callCtx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(jsonPayload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", context.Cause(ctx)
+		}
+		if callCtx.Err() != nil {
+			return "", fmt.Errorf("agent '%s' timed out after %v", a.GetName(), timeout)
+		}
+		return "", fmt.Errorf("agent '%s' command failed: %w: %s", a.GetName(), err, strings.TrimSpace(stderr.String()))
+	}
+
+	response := bytes.TrimSpace(stdout.Bytes())
+	if !json.Valid(response) {
+		return "", fmt.Errorf("agent '%s' returned invalid JSON: %s", a.GetName(), response)
+	}
+	return string(response), nil
+}
+
+// AgentFunc is a Go function that acts as an in-process agent.
+type AgentFunc func(jsonPayload string) (string, error)
+
+// InProcessTransport dispatches payloads to Go functions registered under the agent's endpoint name.
+type InProcessTransport struct {
+	funcs map[string]AgentFunc
+	mu    sync.RWMutex
+}
+
+// NewInProcessTransport creates an InProcessTransport with no registered functions.
+func NewInProcessTransport() *InProcessTransport {
+	return &InProcessTransport{
+		funcs: make(map[string]AgentFunc),
+	}
+}
+
+// Register makes fn reachable by agents whose endpoint is name.
+func (t *InProcessTransport) Register(name string, fn AgentFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.funcs[name] = fn
+}
+
+// Call invokes the function registered under the agent's endpoint. If ctx is done first, the call is
+// abandoned and its eventual response discarded.
+func (t *InProcessTransport) Call(ctx context.Context, a *agent.BaseAgent, jsonPayload string) (string, error) {
+	t.mu.RLock()
+	fn, ok := t.funcs[a.GetEndpoint()]
+	t.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no in-process function registered for agent '%s' at '%s'", a.GetName(), a.GetEndpoint())
+	}
+
+	type result struct {
+		response string
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := fn(jsonPayload)
+		done <- result{response, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.response, r.err
+	case <-ctx.Done():
+		return "", context.Cause(ctx)
+	}
+}
+
+// MockTransport simulates every call with SimulateAPICall.
+type MockTransport struct{}
+
+// Call returns the simulated response, or gives up if ctx is done first.
+func (m *MockTransport) Call(ctx context.Context, a *agent.BaseAgent, jsonPayload string) (string, error) {
+	done := make(chan string, 1)
+	go func() {
+		done <- SimulateAPICall(a, jsonPayload)
+	}()
+
+	select {
+	case response := <-done:
+		return response, nil
+	case <-ctx.Done():
+		return "", context.Cause(ctx)
+	}
+}
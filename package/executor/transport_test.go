@@ -0,0 +1,108 @@
+package executor_test
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+	"trace/package/agent"
+	"trace/package/executor"
+)
+
+// TestSubprocessTransport_Success verifies that the payload is written to stdin and stdout is returned.
+func TestSubprocessTransport_Success(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+
+	a := agent.NewBaseAgent("AG901", "Echo", "Utility", "cat", nil, nil)
+	a.Transport = agent.TransportSubprocess
+
+	response, err := executor.NewExecutor(agent.NewMemoryRegistry(), time.Second).CallAgent(context.Background(), a, `{"echo":true}`)
+	if err != nil {
+		t.Fatalf("CallAgent failed: %v", err)
+	}
+	if response != `{"echo":true}` {
+		t.Errorf("Expected echoed payload, got %s", response)
+	}
+}
+
+// TestSubprocessTransport_Failures verifies non-zero exits and non-JSON output become errors.
+func TestSubprocessTransport_Failures(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+	}{
+		{name: "Non-zero exit", command: "false"},
+		{name: "Invalid JSON", command: "echo not-json"},
+		{name: "Missing command", command: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cmd := strings.Fields(tt.command); len(cmd) > 0 {
+				if _, err := exec.LookPath(cmd[0]); err != nil {
+					t.Skipf("%s not available", cmd[0])
+				}
+			}
+
+			a := agent.NewBaseAgent("AG901", "Broken", "Utility", tt.command, nil, nil)
+			a.Transport = agent.TransportSubprocess
+
+			if _, err := executor.NewExecutor(agent.NewMemoryRegistry(), time.Second).CallAgent(context.Background(), a, `{}`); err == nil {
+				t.Error("Expected error, but got none")
+			}
+		})
+	}
+}
+
+// TestInProcessTransport verifies that registered Go functions are called by endpoint name.
+func TestInProcessTransport(t *testing.T) {
+	e := executor.NewExecutor(agent.NewMemoryRegistry(), time.Second)
+	e.RegisterAgentFunc("geocoder", func(jsonPayload string) (string, error) {
+		return `{"received":` + jsonPayload + `}`, nil
+	})
+
+	a := agent.NewBaseAgent("AG902", "Geocoder", "Utility", "geocoder", nil, nil)
+	a.Transport = agent.TransportInProcess
+
+	response, err := e.CallAgent(context.Background(), a, `{"city":"Chicago"}`)
+	if err != nil {
+		t.Fatalf("CallAgent failed: %v", err)
+	}
+	if response != `{"received":{"city":"Chicago"}}` {
+		t.Errorf("Unexpected response: %s", response)
+	}
+
+	missing := agent.NewBaseAgent("AG903", "Missing", "Utility", "unregistered", nil, nil)
+	missing.Transport = agent.TransportInProcess
+	if _, err := e.CallAgent(context.Background(), missing, `{}`); err == nil {
+		t.Error("Expected error for unregistered function, but got none")
+	}
+}
+
+// TestResolveTransport verifies transports are picked per agent and unknown kinds are rejected.
+func TestResolveTransport(t *testing.T) {
+	e := executor.NewExecutor(agent.NewMemoryRegistry(), time.Second)
+
+	httpAgent := agent.NewBaseAgent("AG904", "Web", "Utility", "http://localhost", nil, nil)
+	transport, err := e.ResolveTransport(httpAgent)
+	if err != nil {
+		t.Fatalf("ResolveTransport failed: %v", err)
+	}
+	if _, ok := transport.(*executor.HTTPTransport); !ok {
+		t.Errorf("Expected agents without a transport to use HTTP, got %T", transport)
+	}
+
+	unknown := agent.NewBaseAgent("AG905", "Carrier", "Utility", "pigeon://", nil, nil)
+	unknown.Transport = "pigeon"
+	if _, err := e.ResolveTransport(unknown); err == nil {
+		t.Error("Expected error for unknown transport kind, but got none")
+	}
+
+	e.RegisterTransport("pigeon", &executor.MockTransport{})
+	if _, err := e.ResolveTransport(unknown); err != nil {
+		t.Errorf("Expected registered transport to resolve, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 	"fmt"
+	"strings"
 )
 
 // Log struct holds information and a timestamp
@@ -81,6 +82,17 @@ func (l *Logger) GetAllLogs() []Log {
 	return logsCopy
 }
 
+// Text returns the information of every log stored in the logger, one per line.
+func (l *Logger) Text() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines := make([]string, len(l.Logs))
+	for i, log := range l.Logs {
+		lines[i] = log.information
+	}
+	return strings.Join(lines, "\n")
+}
+
 // PrintAllLogs prints all logs stored in the logger.
 func (l *Logger) PrintAllLogs() {
 	l.mu.Lock()
@@ -74,3 +74,14 @@ func TestGetAllLogs(t *testing.T) {
 		t.Errorf("Expected logs to match added information, got '%s' and '%s'", allLogs[1].Information(), allLogs[2].Information())
 	}
 }
+
+// TestText checks that the information of every log is returned one per line.
+func TestText(t *testing.T) {
+	log := logger.NewLogger()
+	log.AddLogs([]logger.Log{logger.NewLog("Additional log 1"), logger.NewLog("Additional log 2")})
+
+	expected := "Initialized Logger\nAdditional log 1\nAdditional log 2"
+	if text := log.Text(); text != expected {
+		t.Errorf("Expected text %q, got %q", expected, text)
+	}
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseAfter tests AFTER and DEPENDS ON clauses on tasks in a RUNCON block.
+func TestParseAfter(t *testing.T) {
+	input := `START
+RUNCON {
+    TASK ScheduleFlight AGENT FlightGetter PARAMETERS (OUTPUT=flightInfo) ;
+    TASK CheckWeather AGENT WeatherChecker PARAMETERS (OUTPUT=weather) ;
+    TASK BookHotel AGENT RoomBooker PARAMETERS (flight=flightInfo) RETRY 2 AFTER ScheduleFlight ;
+    TASK PlanDay AGENT Planner PARAMETERS () depends on BookHotel, CheckWeather TIMEOUT 5s ;
+    RUNSEQ {
+        TASK Nested AGENT X PARAMETERS () ;
+    }
+    TASK Summarize AGENT Y PARAMETERS () AFTER RUNSEQ_0 ;
+}
+END`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	con := pr.Statements[0].(*RunConBlock)
+	tests := map[string][]string{
+		"ScheduleFlight": nil,
+		"BookHotel":      {"ScheduleFlight"},
+		"PlanDay":        {"BookHotel", "CheckWeather"},
+		"Summarize":      {"RUNSEQ_0"},
+	}
+	for key, expected := range tests {
+		task := con.Statement(key).(*Task)
+		if !reflect.DeepEqual(task.After, expected) {
+			t.Errorf("Expected %s to run after %v, got %v", key, expected, task.After)
+		}
+	}
+	if hotel := con.Statement("BookHotel").(*Task); hotel.Retry != 2 {
+		t.Errorf("Expected AFTER to combine with RETRY, got retry %d", hotel.Retry)
+	}
+}
+
+// TestAfterErrors tests malformed and misplaced AFTER clauses.
+func TestAfterErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"TASK A AGENT X PARAMETERS () AFTER ;", "line 1, column 36: expected identifier, got ';'"},
+		{"TASK A AGENT X PARAMETERS () AFTER B, ;", "line 1, column 39: expected identifier, got ';'"},
+		{"TASK A AGENT X PARAMETERS () DEPENDS B ;", "line 1, column 38: expected 'ON', got identifier 'B'"},
+		{"TASK A AGENT X PARAMETERS () AFTER B DEPENDS ON C ;", "line 1, column 38: AFTER given more than once for TASK A"},
+		{"RUNCON {\n    TASK A AGENT X PARAMETERS () AFTER A ;\n}", "line 2, column 5: TASK A cannot run AFTER itself"},
+		{"RUNCON {\n    TASK A AGENT X PARAMETERS () AFTER B ;\n}", "line 2, column 5: TASK A runs AFTER 'B', which is not in the RUNCON block opened at 1:1"},
+		{"TASK A AGENT X PARAMETERS () COMPENSATE WITH TASK U AGENT X PARAMETERS () AFTER B ;", "line 1, column 46: compensation task U cannot have AFTER; it runs when the script fails"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
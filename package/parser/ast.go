@@ -0,0 +1,292 @@
+package parser
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Statement is a node that can appear in the body of a script or block: a task or a block of statements.
+// The set of statements is closed; only types in this package implement it.
+type Statement interface {
+	GetSpan() Span
+	statementNode()
+}
+
+// Data represents a global data declaration.
+type Data struct {
+	DataName     string
+	DataType     string
+	InitialValue string
+	Mu           sync.Mutex
+	Span         Span
+}
+
+// Permission represents permissions assigned to an agent.
+type Permission struct {
+	AgentName       string
+	DataPermissions map[string][]string // Map of data names to permissions
+	Span            Span                // Span of the agent's first PERM statement
+	DataSpans       map[string]Span     // Where each data name was first granted
+}
+
+// Task represents a task to be executed.
+type Task struct {
+	TaskName     string
+	AgentName    string
+	Parameters   map[string]Parameter
+	Retry        int           // Extra attempts after the first call to the agent fails
+	Backoff      Backoff       // Wait between attempts
+	Timeout      time.Duration // Limit on each attempt; zero for the executor's default
+	Compensation *Task         // Task that undoes this one if the script fails after it completed; nil for none
+	After        []string      // Keys of the statements in the same RUNCON or AUTO block that must finish first
+	Span         Span
+}
+
+// Backoff strategies for retried tasks.
+const (
+	BackoffFixed       = "fixed"
+	BackoffLinear      = "linear"
+	BackoffExponential = "exponential"
+)
+
+// Backoff describes how long a task waits before each retry.
+type Backoff struct {
+	Strategy string        // One of the Backoff* strategies; empty for none
+	Delay    time.Duration // Base delay; zero for the executor's default
+}
+
+// Output returns the name of the global data the task's response is written to, if it has an OUTPUT parameter.
+func (t *Task) Output() (string, bool) {
+	param, ok := t.Parameters["OUTPUT"]
+	return param.Value, ok
+}
+
+// Parameter is the value of a task parameter, either a literal or a reference to global data.
+type Parameter struct {
+	Value string
+	Type  TokenType // STRING or NUMBER for literals, IDENT for references
+}
+
+// IsReference reports whether the parameter names global data rather than holding a literal value.
+func (p Parameter) IsReference() bool {
+	return p.Type == IDENT
+}
+
+// String returns the parameter as written in the script, quoting string literals.
+func (p Parameter) String() string {
+	if p.Type == STRING {
+		return strconv.Quote(p.Value)
+	}
+	return p.Value
+}
+
+// ParentRequest represents the root of the parsed script.
+type ParentRequest struct {
+	Statements  []Statement            // Slice of tasks and blocks (RUNSEQ, RUNCON, IF and loops)
+	GlobalData  map[string]*Data       // Mapping of data name to Data
+	Permissions map[string]*Permission // Mapping of agent name to Permission
+	OnError     string                 // ON ERROR policy from the script header; empty for CONTINUE
+	Deadline    time.Duration          // DEADLINE for the whole run; zero for none
+}
+
+// Error policies chosen with ON ERROR. A block without one inherits the policy of the enclosing block,
+// and the script defaults to CONTINUE.
+const (
+	OnErrorStop     = "STOP"     // Stop at the first failure, skipping the rest of the sequence and cancelling concurrent siblings
+	OnErrorContinue = "CONTINUE" // Run everything and report the failures at the end
+)
+
+// RunSeqBlock represents a RUNSEQ block.
+type RunSeqBlock struct {
+	Statements []Statement   // Ordered slice of tasks and blocks
+	OnError    string        // ON ERROR policy; empty to inherit
+	Deadline   time.Duration // DEADLINE for the whole block; zero for none
+	Span       Span
+}
+
+// RunConBlock represents a RUNCON block.
+type RunConBlock struct {
+	Keys       []string      // Stable key of each child: its task name, or KEYWORD_n (such as RUNSEQ_0) for nested blocks
+	Statements []Statement   // Tasks and blocks in declaration order, parallel to Keys
+	OnError    string        // ON ERROR policy; empty to inherit
+	Deadline   time.Duration // DEADLINE for the whole block; zero for none
+	Max        int           // Most children running at once; zero for no limit
+	Span       Span
+}
+
+// Statement returns the child with the given key, or nil if there is none.
+func (b *RunConBlock) Statement(key string) Statement {
+	for i, k := range b.Keys {
+		if k == key {
+			return b.Statements[i]
+		}
+	}
+	return nil
+}
+
+// AutoBlock represents an AUTO block. Its statements run concurrently, each as soon as the statements it
+// depends on have finished: those that write data it reads, or that read or write data it writes, earlier in
+// the block, and those named in its AFTER clause.
+type AutoBlock struct {
+	Keys       []string      // Stable key of each child, as in RunConBlock
+	Statements []Statement   // Tasks and blocks in declaration order, parallel to Keys
+	OnError    string        // ON ERROR policy; empty to inherit
+	Deadline   time.Duration // DEADLINE for the whole block; zero for none
+	Span       Span
+}
+
+// Statement returns the child with the given key, or nil if there is none.
+func (b *AutoBlock) Statement(key string) Statement {
+	for i, k := range b.Keys {
+		if k == key {
+			return b.Statements[i]
+		}
+	}
+	return nil
+}
+
+// RunAnyBlock represents a RUNANY or RUNQUORUM block. Its children run concurrently until Quorum of them have
+// succeeded; the rest are cancelled, and only the winners' outputs are kept.
+type RunAnyBlock struct {
+	Any        bool        // Written as RUNANY, which is RUNQUORUM 1
+	Quorum     int         // Children that must succeed
+	Statements []Statement // Tasks and blocks in declaration order
+	Deadline   time.Duration
+	Span       Span
+}
+
+// Keyword returns RUNANY or RUNQUORUM.
+func (b *RunAnyBlock) Keyword() string {
+	if b.Any {
+		return "RUNANY"
+	}
+	return "RUNQUORUM"
+}
+
+// Condition operators.
+const (
+	OpEqual        = "=="
+	OpNotEqual     = "!="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpContains     = "CONTAINS"
+	OpIsEmpty      = "IS EMPTY"
+	OpIsNotEmpty   = "IS NOT EMPTY"
+)
+
+// comparisonOperators maps comparison tokens to their operators.
+var comparisonOperators = map[TokenType]string{
+	EQ:     OpEqual,
+	NOT_EQ: OpNotEqual,
+	LT:     OpLess,
+	LT_EQ:  OpLessEqual,
+	GT:     OpGreater,
+	GT_EQ:  OpGreaterEqual,
+}
+
+// Condition tests global data values in an IF statement.
+type Condition struct {
+	Left     Parameter
+	Operator string    // One of the Op* operators
+	Right    Parameter // Unused by IS EMPTY and IS NOT EMPTY
+	Span     Span
+}
+
+// String returns the condition as written in the script.
+func (c *Condition) String() string {
+	if c.Operator == OpIsEmpty || c.Operator == OpIsNotEmpty {
+		return c.Left.String() + " " + c.Operator
+	}
+	return c.Left.String() + " " + c.Operator + " " + c.Right.String()
+}
+
+// IfBlock represents an IF statement. An ELSE IF is parsed as an IfBlock that is the only statement of Else.
+type IfBlock struct {
+	Condition *Condition
+	Then      []Statement
+	Else      []Statement // Nil when there is no ELSE branch
+	Span      Span
+}
+
+// ForEachBlock represents a FOREACH loop, which runs its body once for each item of a list held in global data.
+type ForEachBlock struct {
+	Item        string // Loop variable, readable by every agent in the body
+	List        string // Global data holding a JSON array
+	Concurrent  bool   // Run iterations in parallel rather than in order
+	CollectVar  string // Per-iteration variable whose final value is collected; empty without COLLECT
+	CollectInto string // Global data that receives the collected values as a JSON array
+	Body        []Statement
+	Span        Span
+}
+
+// LoopBlock represents a bounded WHILE or UNTIL loop. The condition is checked before each iteration.
+type LoopBlock struct {
+	Until     bool // UNTIL loops run until the condition holds; WHILE loops run while it holds
+	Condition *Condition
+	Max       int           // Most iterations allowed before the loop fails
+	Every     time.Duration // Pause between iterations; zero for none
+	Body      []Statement
+	Span      Span
+}
+
+// Keyword returns WHILE or UNTIL.
+func (b *LoopBlock) Keyword() string {
+	if b.Until {
+		return "UNTIL"
+	}
+	return "WHILE"
+}
+
+// TryBlock represents TRY { ... } with an optional CATCH and FINALLY. At least one of them is present.
+type TryBlock struct {
+	Body    []Statement
+	Catch   []Statement // Nil if there is no CATCH
+	Finally []Statement // Nil if there is no FINALLY
+	Span    Span
+}
+
+// Names of the data a CATCH body can read to learn what failed.
+const (
+	CatchTaskVar    = "errorTask"    // Name of the task that failed, or empty if the failure was not a task's
+	CatchMessageVar = "errorMessage" // The error message
+)
+
+// GetSpan returns the source range of the task.
+func (t *Task) GetSpan() Span { return t.Span }
+
+// GetSpan returns the source range of the block, from RUNSEQ to its closing brace.
+func (b *RunSeqBlock) GetSpan() Span { return b.Span }
+
+// GetSpan returns the source range of the block, from RUNCON to its closing brace.
+func (b *RunConBlock) GetSpan() Span { return b.Span }
+
+// GetSpan returns the source range of the block, from AUTO to its closing brace.
+func (b *AutoBlock) GetSpan() Span { return b.Span }
+
+// GetSpan returns the source range of the block, from RUNANY or RUNQUORUM to its closing brace.
+func (b *RunAnyBlock) GetSpan() Span { return b.Span }
+
+// GetSpan returns the source range of the statement, from IF to the closing brace of its last branch.
+func (b *IfBlock) GetSpan() Span { return b.Span }
+
+// GetSpan returns the source range of the loop, from FOREACH to its closing brace.
+func (b *ForEachBlock) GetSpan() Span { return b.Span }
+
+// GetSpan returns the source range of the loop, from WHILE or UNTIL to its closing brace.
+func (b *LoopBlock) GetSpan() Span { return b.Span }
+
+// GetSpan returns the source range of the block, from TRY to the closing brace of its last clause.
+func (b *TryBlock) GetSpan() Span { return b.Span }
+
+func (*Task) statementNode()         {}
+func (*RunSeqBlock) statementNode()  {}
+func (*RunConBlock) statementNode()  {}
+func (*RunAnyBlock) statementNode()  {}
+func (*AutoBlock) statementNode()    {}
+func (*IfBlock) statementNode()      {}
+func (*ForEachBlock) statementNode() {}
+func (*LoopBlock) statementNode()    {}
+func (*TryBlock) statementNode()     {}
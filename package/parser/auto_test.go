@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestParseAutoBlock tests AUTO blocks with their options, keys and AFTER clauses.
+func TestParseAutoBlock(t *testing.T) {
+	input := `START
+AUTO ON ERROR STOP DEADLINE 1m {
+    TASK ScheduleFlight AGENT FlightGetter PARAMETERS (OUTPUT=flightInfo) ;
+    IF flightInfo IS NOT EMPTY {
+        TASK BookHotel AGENT RoomBooker PARAMETERS (flight=flightInfo) ;
+    }
+    TASK Notify AGENT Notifier PARAMETERS () AFTER IF_0 ;
+}
+END`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	auto := pr.Statements[0].(*AutoBlock)
+	if auto.OnError != OnErrorStop || auto.Deadline != time.Minute {
+		t.Errorf("Expected policy %s and a 1m deadline, got %q and %s", OnErrorStop, auto.OnError, auto.Deadline)
+	}
+	if expected := []string{"ScheduleFlight", "IF_0", "Notify"}; !reflect.DeepEqual(auto.Keys, expected) {
+		t.Errorf("Expected keys %v, got %v", expected, auto.Keys)
+	}
+	if notify := auto.Statement("Notify").(*Task); !reflect.DeepEqual(notify.After, []string{"IF_0"}) {
+		t.Errorf("Expected Notify to run after IF_0, got %v", notify.After)
+	}
+}
+
+// TestAutoBlockErrors tests malformed AUTO blocks.
+func TestAutoBlockErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"AUTO TASK A AGENT X PARAMETERS () ; }", "line 1, column 6: expected '{', got identifier 'TASK'"},
+		{"AUTO {\n    TASK A AGENT X PARAMETERS () ;\n    TASK A AGENT Y PARAMETERS () ;\n}", "line 3, column 5: duplicate task name 'A' in AUTO block, first declared at 2:5"},
+		{"AUTO {\n    TASK A AGENT X PARAMETERS () AFTER B ;\n}", "line 2, column 5: TASK A runs AFTER 'B', which is not in the AUTO block opened at 1:1"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseCompensation tests tasks that declare a compensating task.
+func TestParseCompensation(t *testing.T) {
+	input := `START
+TASK BookHotel AGENT RoomBooker PARAMETERS (location=location, OUTPUT=hotelInfo) RETRY 2
+    COMPENSATE WITH TASK CancelHotel AGENT RoomBooker PARAMETERS (booking=hotelInfo) RETRY 5 ;
+TASK ScheduleRide AGENT UberScheduler PARAMETERS () ;
+END`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	book := pr.Statements[0].(*Task)
+	if got, want := book.Compensation.Span, (Span{Start: Position{3, 21}, End: Position{3, 94}}); got != want {
+		t.Errorf("Compensation span: expected %+v, got %+v", want, got)
+	}
+	clearSpans(pr)
+
+	expected := &Task{
+		TaskName:  "BookHotel",
+		AgentName: "RoomBooker",
+		Parameters: map[string]Parameter{
+			"location": {Value: "location", Type: IDENT},
+			"OUTPUT":   {Value: "hotelInfo", Type: IDENT},
+		},
+		Retry: 2,
+		Compensation: &Task{
+			TaskName:   "CancelHotel",
+			AgentName:  "RoomBooker",
+			Parameters: map[string]Parameter{"booking": {Value: "hotelInfo", Type: IDENT}},
+			Retry:      5,
+		},
+	}
+	if !reflect.DeepEqual(book, expected) {
+		t.Errorf("Unexpected task:\nExpected: %+v\nGot: %+v", expected, book)
+	}
+	if ride := pr.Statements[1].(*Task); ride.Compensation != nil {
+		t.Errorf("Expected no compensation for ScheduleRide, got %+v", ride.Compensation)
+	}
+}
+
+// TestCompensationErrors tests malformed COMPENSATE clauses.
+func TestCompensationErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"TASK A AGENT X PARAMETERS () COMPENSATE TASK B AGENT X PARAMETERS () ;", "line 1, column 41: expected 'WITH', got identifier 'TASK'"},
+		{"TASK A AGENT X PARAMETERS () COMPENSATE WITH B AGENT X PARAMETERS () ;", "line 1, column 46: expected 'TASK', got identifier 'B'"},
+		{"TASK A AGENT X PARAMETERS () COMPENSATE WITH TASK B AGENT X ;", "line 1, column 61: expected 'PARAMETERS', got ';'"},
+		{"TASK A AGENT X PARAMETERS () COMPENSATE WITH TASK B AGENT X PARAMETERS () COMPENSATE WITH TASK C AGENT X PARAMETERS () ;",
+			"line 1, column 75: compensation task B cannot have its own COMPENSATE"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
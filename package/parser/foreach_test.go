@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseForEachBlock tests sequential and concurrent FOREACH loops with and without COLLECT.
+func TestParseForEachBlock(t *testing.T) {
+	input := `START
+FOREACH destination IN destinations COLLECT flight INTO flights {
+    TASK SearchFlight AGENT FlightGetter PARAMETERS (destination=destination, OUTPUT=flight) ;
+}
+RUNCON {
+    foreach package in packages concurrent {
+        TASK TrackPackage AGENT PackageTracker PARAMETERS (tracking_number=package) ;
+    }
+}
+END`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	sequential := pr.Statements[0].(*ForEachBlock)
+	if got, want := sequential.Span, (Span{Start: Position{2, 1}, End: Position{4, 1}}); got != want {
+		t.Errorf("FOREACH span: expected %+v, got %+v", want, got)
+	}
+	clearSpans(pr)
+
+	expected := &ForEachBlock{
+		Item:        "destination",
+		List:        "destinations",
+		CollectVar:  "flight",
+		CollectInto: "flights",
+		Body: []Statement{
+			&Task{
+				TaskName:  "SearchFlight",
+				AgentName: "FlightGetter",
+				Parameters: map[string]Parameter{
+					"destination": {Value: "destination", Type: IDENT},
+					"OUTPUT":      {Value: "flight", Type: IDENT},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(sequential, expected) {
+		t.Errorf("Unexpected FOREACH:\nExpected: %+v\nGot: %+v", expected, sequential)
+	}
+
+	con := pr.Statements[1].(*RunConBlock)
+	if !reflect.DeepEqual(con.Keys, []string{"FOREACH_0"}) {
+		t.Errorf("Expected RUNCON keys [FOREACH_0], got %v", con.Keys)
+	}
+	concurrent := con.Statements[0].(*ForEachBlock)
+	if !concurrent.Concurrent || concurrent.Item != "package" || concurrent.List != "packages" || concurrent.CollectVar != "" {
+		t.Errorf("Unexpected concurrent FOREACH: %+v", concurrent)
+	}
+}
+
+// TestForEachBlockErrors tests error messages for malformed loops.
+func TestForEachBlockErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"FOREACH item packages { }", "line 1, column 14: expected 'IN', got identifier 'packages'"},
+		{"FOREACH item IN { }", "line 1, column 17: expected identifier, got '{'"},
+		{"FOREACH item IN list COLLECT result { }", "line 1, column 37: expected 'INTO', got '{'"},
+		{"FOREACH item IN list PARALLEL { }", "line 1, column 22: expected '{', got identifier 'PARALLEL'"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
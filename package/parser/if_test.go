@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestLexerComparisonOperators tests that one- and two-character operators are lexed as single tokens.
+func TestLexerComparisonOperators(t *testing.T) {
+	l := NewLexer("= == != < <= > >= !")
+	expected := []Token{
+		{Type: EQUAL, Literal: "="},
+		{Type: EQ, Literal: "=="},
+		{Type: NOT_EQ, Literal: "!="},
+		{Type: LT, Literal: "<"},
+		{Type: LT_EQ, Literal: "<="},
+		{Type: GT, Literal: ">"},
+		{Type: GT_EQ, Literal: ">="},
+		{Type: ILLEGAL, Literal: "!"},
+		{Type: EOF, Literal: ""},
+	}
+	for i, want := range expected {
+		got := l.NextToken()
+		if got.Type != want.Type || got.Literal != want.Literal {
+			t.Errorf("Token %d: expected %s %q, got %s %q", i, want.Type, want.Literal, got.Type, got.Literal)
+		}
+	}
+}
+
+// TestParseIfBlock tests IF statements with ELSE and ELSE IF branches.
+func TestParseIfBlock(t *testing.T) {
+	input := `START
+IF weatherInfo CONTAINS "sunny" {
+    TASK BookHotel AGENT RoomBooker PARAMETERS () ;
+} ELSE IF temperature >= 20 {
+    TASK BookCabin AGENT RoomBooker PARAMETERS () ;
+} ELSE {
+    TASK StayHome AGENT Planner PARAMETERS () ;
+}
+if notes is not empty {
+}
+END`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+	clearSpans(pr)
+
+	expected := []Statement{
+		&IfBlock{
+			Condition: &Condition{
+				Left:     Parameter{Value: "weatherInfo", Type: IDENT},
+				Operator: OpContains,
+				Right:    Parameter{Value: "sunny", Type: STRING},
+			},
+			Then: []Statement{
+				&Task{TaskName: "BookHotel", AgentName: "RoomBooker", Parameters: map[string]Parameter{}},
+			},
+			Else: []Statement{
+				&IfBlock{
+					Condition: &Condition{
+						Left:     Parameter{Value: "temperature", Type: IDENT},
+						Operator: OpGreaterEqual,
+						Right:    Parameter{Value: "20", Type: NUMBER},
+					},
+					Then: []Statement{
+						&Task{TaskName: "BookCabin", AgentName: "RoomBooker", Parameters: map[string]Parameter{}},
+					},
+					Else: []Statement{
+						&Task{TaskName: "StayHome", AgentName: "Planner", Parameters: map[string]Parameter{}},
+					},
+				},
+			},
+		},
+		&IfBlock{
+			Condition: &Condition{
+				Left:     Parameter{Value: "notes", Type: IDENT},
+				Operator: OpIsNotEmpty,
+			},
+			Then: []Statement{},
+		},
+	}
+	if !reflect.DeepEqual(pr.Statements, expected) {
+		t.Errorf("Unexpected statements:\nExpected: %+v\nGot: %+v", expected, pr.Statements)
+	}
+
+	if got := expected[0].(*IfBlock).Condition.String(); got != `weatherInfo CONTAINS "sunny"` {
+		t.Errorf("Unexpected condition string: %s", got)
+	}
+	if got := expected[1].(*IfBlock).Condition.String(); got != "notes IS NOT EMPTY" {
+		t.Errorf("Unexpected condition string: %s", got)
+	}
+}
+
+// TestIfBlockSpan tests that an IF statement spans all of its branches.
+func TestIfBlockSpan(t *testing.T) {
+	input := "IF a == 1 {\n} ELSE IF a == 2 {\n} ELSE {\n}"
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	ifBlock := pr.Statements[0].(*IfBlock)
+	if got, want := ifBlock.Span, (Span{Start: Position{1, 1}, End: Position{4, 1}}); got != want {
+		t.Errorf("IF span: expected %+v, got %+v", want, got)
+	}
+	if got, want := ifBlock.Condition.Span, (Span{Start: Position{1, 4}, End: Position{1, 9}}); got != want {
+		t.Errorf("Condition span: expected %+v, got %+v", want, got)
+	}
+	if got, want := ifBlock.Else[0].GetSpan().Start, (Position{2, 8}); got != want {
+		t.Errorf("ELSE IF start: expected %+v, got %+v", want, got)
+	}
+}
+
+// TestIfBlockErrors tests error messages for malformed conditions.
+func TestIfBlockErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"IF a = 1 { }", "line 1, column 6: expected a comparison, 'IS' or 'CONTAINS' in condition, got '='"},
+		{"IF a IS FULL { }", "line 1, column 9: expected 'EMPTY', got identifier 'FULL'"},
+		{"IF a CONTAINS { }", "line 1, column 15: expected a value in condition, got '{'"},
+		{"IF a == 1 TASK", "line 1, column 11: expected '{', got identifier 'TASK'"},
+		{"IF a == 1 { } ELSE TASK", "line 1, column 20: expected '{', got identifier 'TASK'"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestLexerDurations tests that numbers followed by a unit are lexed as durations.
+func TestLexerDurations(t *testing.T) {
+	l := NewLexer("500ms 10s 1m30s 1.5h 42")
+	expected := []Token{
+		{Type: DURATION, Literal: "500ms"},
+		{Type: DURATION, Literal: "10s"},
+		{Type: DURATION, Literal: "1m30s"},
+		{Type: DURATION, Literal: "1.5h"},
+		{Type: NUMBER, Literal: "42"},
+	}
+	for i, want := range expected {
+		got := l.NextToken()
+		if got.Type != want.Type || got.Literal != want.Literal {
+			t.Errorf("Token %d: expected %s %q, got %s %q", i, want.Type, want.Literal, got.Type, got.Literal)
+		}
+	}
+}
+
+// TestParseLoopBlock tests WHILE and UNTIL loops with and without EVERY.
+func TestParseLoopBlock(t *testing.T) {
+	input := `START
+UNTIL packageStatus CONTAINS "delivered" MAX 10 EVERY 30s {
+    TASK TrackPackage AGENT PackageTracker PARAMETERS (tracking_number=trackingNumber, OUTPUT=packageStatus) ;
+}
+RUNCON {
+    while attempts < 3 max 5 {
+    }
+}
+END`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	until := pr.Statements[0].(*LoopBlock)
+	if got, want := until.Span, (Span{Start: Position{2, 1}, End: Position{4, 1}}); got != want {
+		t.Errorf("UNTIL span: expected %+v, got %+v", want, got)
+	}
+	clearSpans(pr)
+
+	expected := &LoopBlock{
+		Until: true,
+		Condition: &Condition{
+			Left:     Parameter{Value: "packageStatus", Type: IDENT},
+			Operator: OpContains,
+			Right:    Parameter{Value: "delivered", Type: STRING},
+		},
+		Max:   10,
+		Every: 30 * time.Second,
+		Body: []Statement{
+			&Task{
+				TaskName:  "TrackPackage",
+				AgentName: "PackageTracker",
+				Parameters: map[string]Parameter{
+					"tracking_number": {Value: "trackingNumber", Type: IDENT},
+					"OUTPUT":          {Value: "packageStatus", Type: IDENT},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(until, expected) {
+		t.Errorf("Unexpected UNTIL loop:\nExpected: %+v\nGot: %+v", expected, until)
+	}
+
+	con := pr.Statements[1].(*RunConBlock)
+	if !reflect.DeepEqual(con.Keys, []string{"WHILE_0"}) {
+		t.Errorf("Expected RUNCON keys [WHILE_0], got %v", con.Keys)
+	}
+	while := con.Statements[0].(*LoopBlock)
+	if while.Until || while.Max != 5 || while.Every != 0 {
+		t.Errorf("Unexpected WHILE loop: %+v", while)
+	}
+}
+
+// TestLoopBlockErrors tests that loops must be bounded and durations must be valid.
+func TestLoopBlockErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"UNTIL done == 1 { }", "line 1, column 17: expected 'MAX', got '{'"},
+		{"UNTIL done == 1 MAX 0 { }", "line 1, column 21: MAX must be a positive whole number, got number '0'"},
+		{"UNTIL done == 1 MAX 2.5 { }", "line 1, column 21: MAX must be a positive whole number, got number '2.5'"},
+		{"WHILE a == 1 MAX 3 EVERY 10 { }", "line 1, column 26: expected duration, got number '10'"},
+		{"WHILE a == 1 MAX 3 EVERY 10parsecs { }", "line 1, column 26: invalid duration '10parsecs'; use a positive number with a unit such as 500ms, 10s or 1m"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
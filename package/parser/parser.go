@@ -2,8 +2,9 @@ package parser
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
-	"sync"
+	"time"
 	"unicode"
 )
 
@@ -28,12 +29,86 @@ const (
 	COMMA   // ,
 	SEMICOL // ;
 	EQUAL   // =
+	EQ      // ==
+	NOT_EQ  // !=
+	LT      // <
+	LT_EQ   // <=
+	GT      // >
+	GT_EQ   // >=
+
+	DURATION // duration literal, such as 500ms or 1m30s
 )
 
+// tokenNames holds the human-readable name of each token type.
+var tokenNames = map[TokenType]string{
+	ILLEGAL: "illegal character",
+	EOF:     "end of input",
+	WS:      "whitespace",
+	COMMENT: "comment",
+	IDENT:   "identifier",
+	STRING:  "string",
+	NUMBER:  "number",
+	LBRACE:  "'{'",
+	RBRACE:  "'}'",
+	LPAREN:  "'('",
+	RPAREN:  "')'",
+	COMMA:   "','",
+	SEMICOL: "';'",
+	EQUAL:   "'='",
+	EQ:      "'=='",
+	NOT_EQ:  "'!='",
+	LT:      "'<'",
+	LT_EQ:   "'<='",
+	GT:      "'>'",
+	GT_EQ:   "'>='",
+
+	DURATION: "duration",
+}
+
+// String returns the human-readable name of the token type.
+func (t TokenType) String() string {
+	if name, ok := tokenNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("token(%d)", int(t))
+}
+
+// Position is a line and column in the source, both starting at 1.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// String returns the position as line:column.
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the source range covered by a node, from the start of its first token to the start of its last token.
+type Span struct {
+	Start Position
+	End   Position
+}
+
 // Token represents a lexical token.
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
+}
+
+// describeToken names a token for error messages, including its text where that helps.
+func describeToken(tok Token) string {
+	switch tok.Type {
+	case IDENT, NUMBER, DURATION:
+		return fmt.Sprintf("%s '%s'", tok.Type, tok.Literal)
+	case STRING:
+		return fmt.Sprintf("%s %q", tok.Type, tok.Literal)
+	case ILLEGAL:
+		return fmt.Sprintf("%s '%s'", tok.Type, tok.Literal)
+	default:
+		return tok.Type.String()
+	}
 }
 
 // Lexer represents a lexer for AICL.
@@ -42,17 +117,24 @@ type Lexer struct {
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position (after current char)
 	ch           byte // current char under examination
+	line         int  // line of the current char
+	column       int  // column of the current char
 }
 
 // NewLexer initializes a new Lexer with the input.
 func NewLexer(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 // readChar advances the lexer to the next character.
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
 	if l.readPosition >= len(l.input) {
 		l.ch = 0 // ASCII code for NUL, signifies EOF
 	} else {
@@ -62,11 +144,18 @@ func (l *Lexer) readChar() {
 	l.readPosition++
 }
 
-// NextToken lexes the next token from the input.
+// NextToken lexes the next token from the input and records where it starts.
 func (l *Lexer) NextToken() Token {
-	var tok Token
-
 	l.skipWhitespace()
+	pos := Position{Line: l.line, Column: l.column}
+	tok := l.lexToken()
+	tok.Pos = pos
+	return tok
+}
+
+// lexToken lexes the token starting at the current character.
+func (l *Lexer) lexToken() Token {
+	var tok Token
 
 	switch l.ch {
 	case '{':
@@ -82,7 +171,29 @@ func (l *Lexer) NextToken() Token {
 	case ';':
 		tok = newToken(SEMICOL, l.ch)
 	case '=':
-		tok = newToken(EQUAL, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(EQ)
+		} else {
+			tok = newToken(EQUAL, l.ch)
+		}
+	case '!':
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(NOT_EQ)
+		} else {
+			tok = newToken(ILLEGAL, l.ch)
+		}
+	case '<':
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(LT_EQ)
+		} else {
+			tok = newToken(LT, l.ch)
+		}
+	case '>':
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(GT_EQ)
+		} else {
+			tok = newToken(GT, l.ch)
+		}
 	case '/':
 		if l.peekChar() == '/' {
 			l.readChar()
@@ -114,6 +225,11 @@ func (l *Lexer) NextToken() Token {
 		} else if isDigit(l.ch) {
 			tok.Type = NUMBER
 			tok.Literal = l.readNumber()
+			// A number directly followed by a unit is a duration
+			if isLetter(l.ch) {
+				tok.Type = DURATION
+				tok.Literal += l.readDurationUnits()
+			}
 			return tok
 		} else {
 			tok = newToken(ILLEGAL, l.ch)
@@ -129,6 +245,13 @@ func newToken(tokenType TokenType, ch byte) Token {
 	return Token{Type: tokenType, Literal: string(ch)}
 }
 
+// readTwoCharToken consumes the current character and the next one as a single token.
+func (l *Lexer) readTwoCharToken(tokenType TokenType) Token {
+	ch := l.ch
+	l.readChar()
+	return Token{Type: tokenType, Literal: string(ch) + string(l.ch)}
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.ch != 0 && unicode.IsSpace(rune(l.ch)) {
 		l.readChar()
@@ -151,6 +274,15 @@ func (l *Lexer) readNumber() string {
 	return l.input[position:l.position]
 }
 
+// readDurationUnits reads the rest of a duration after its first number, such as the "m30s" of 1m30s.
+func (l *Lexer) readDurationUnits() string {
+	position := l.position
+	for isLetter(l.ch) || isDigit(l.ch) || l.ch == '.' {
+		l.readChar()
+	}
+	return l.input[position:l.position]
+}
+
 func (l *Lexer) readString() string {
 	var result strings.Builder
 	l.readChar() // skip opening quote
@@ -222,42 +354,15 @@ func isDigit(ch byte) bool {
 	return unicode.IsDigit(rune(ch))
 }
 
-// Data represents a global data declaration.
-type Data struct {
-	DataName     string
-	DataType     string
-	InitialValue string
-	Mu 			 sync.Mutex
-}
-
-// Permission represents permissions assigned to an agent.
-type Permission struct {
-	AgentName      string
-	DataPermissions map[string][]string // Map of data names to permissions
-}
-
-// Task represents a task to be executed.
-type Task struct {
-	TaskName   string
-	AgentName  string
-	Parameters map[string]string
-}
-
-// ParentRequest represents the root of the parsed script.
-type ParentRequest struct {
-	Statements  []interface{}          // Slice of tasks and blocks (RUNSEQ, RUNCON)
-	GlobalData  map[string]*Data       // Mapping of data name to Data
-	Permissions map[string]*Permission // Mapping of agent name to Permission
+// ParseError is a syntax error at a position in the source.
+type ParseError struct {
+	Pos     Position
+	Message string
 }
 
-// RunSeqBlock represents a RUNSEQ block.
-type RunSeqBlock struct {
-	Statements []interface{} // Ordered slice of tasks and blocks
-}
-
-// RunConBlock represents a RUNCON block.
-type RunConBlock struct {
-	Statements map[string]interface{} // Mapping of task/block names to tasks and blocks
+// Error returns the message prefixed with its line and column.
+func (e ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Pos.Line, e.Pos.Column, e.Message)
 }
 
 // Parser represents the parser for AICL.
@@ -265,7 +370,7 @@ type Parser struct {
 	lexer     *Lexer
 	curToken  Token
 	peekToken Token
-	errors    []string
+	errors    []ParseError
 
 	parentRequest *ParentRequest
 }
@@ -274,9 +379,9 @@ type Parser struct {
 func NewParser(l *Lexer) *Parser {
 	p := &Parser{
 		lexer:  l,
-		errors: []string{},
+		errors: []ParseError{},
 		parentRequest: &ParentRequest{
-			Statements:  []interface{}{},
+			Statements:  []Statement{},
 			GlobalData:  make(map[string]*Data),
 			Permissions: make(map[string]*Permission),
 		},
@@ -301,6 +406,11 @@ func (p *Parser) peekTokenIs(t TokenType) bool {
 	return p.peekToken.Type == t
 }
 
+// errorAt records a parse error at the given position.
+func (p *Parser) errorAt(pos Position, format string, args ...interface{}) {
+	p.errors = append(p.errors, ParseError{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
 // Helper functions to check for keywords
 func (p *Parser) curTokenIsKeyword(keyword string) bool {
 	return p.curToken.Type == IDENT && strings.ToUpper(p.curToken.Literal) == strings.ToUpper(keyword)
@@ -314,7 +424,7 @@ func (p *Parser) expectCurTokenKeyword(keyword string) bool {
 	if p.curTokenIsKeyword(keyword) {
 		return true
 	} else {
-		p.errors = append(p.errors, fmt.Sprintf("Expected current token to be '%s', got '%s' instead", keyword, p.curToken.Literal))
+		p.errorAt(p.curToken.Pos, "expected '%s', got %s", keyword, describeToken(p.curToken))
 		return false
 	}
 }
@@ -330,8 +440,7 @@ func (p *Parser) expectPeekKeyword(keyword string) bool {
 }
 
 func (p *Parser) peekErrorKeyword(keyword string) {
-	msg := fmt.Sprintf("Expected next token to be '%s', got '%s' instead", keyword, p.peekToken.Literal)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.peekToken.Pos, "expected '%s', got %s", keyword, describeToken(p.peekToken))
 }
 
 // ParseProgram parses the entire program.
@@ -343,7 +452,7 @@ func (p *Parser) ParseProgram() *ParentRequest {
 			continue
 		}
 		if p.curTokenIsKeyword("START") {
-			p.nextToken()
+			p.parseHeader()
 		} else if p.curTokenIsKeyword("END") {
 			p.nextToken()
 		} else if p.curTokenIsKeyword("DATA") {
@@ -353,23 +462,8 @@ func (p *Parser) ParseProgram() *ParentRequest {
 			}
 		} else if p.curTokenIsKeyword("PERM") {
 			p.parsePermission()
-		} else if p.curTokenIsKeyword("RUNSEQ") {
-			seqBlock := p.parseRunSeqBlock()
-			if seqBlock != nil {
-				p.parentRequest.Statements = append(p.parentRequest.Statements, seqBlock)
-			}
-		} else if p.curTokenIsKeyword("RUNCON") {
-			conBlock := p.parseRunConBlock()
-			if conBlock != nil {
-				p.parentRequest.Statements = append(p.parentRequest.Statements, conBlock)
-			}
-		} else if p.curTokenIsKeyword("TASK") {
-			task := p.parseTask()
-			if task != nil {
-				p.parentRequest.Statements = append(p.parentRequest.Statements, task)
-			}
-		} else {
-			p.nextToken()
+		} else if stmt := p.parseStatement(); stmt != nil {
+			p.parentRequest.Statements = append(p.parentRequest.Statements, stmt)
 		}
 	}
 	return p.parentRequest
@@ -377,8 +471,89 @@ func (p *Parser) ParseProgram() *ParentRequest {
 
 // Parsing functions
 
+// parseHeader parses START and the clauses that follow it, leaving the current token after them.
+func (p *Parser) parseHeader() {
+	if !p.parseRunOptions("in the script header", &p.parentRequest.OnError, &p.parentRequest.Deadline, nil) {
+		return
+	}
+	p.nextToken()
+}
+
+// parseRunOptions parses the ON ERROR, DEADLINE and MAX clauses following the current token, in any order,
+// leaving the current token on the last token of the last one. where completes error messages, and a nil
+// onError or max means that clause is not allowed.
+func (p *Parser) parseRunOptions(where string, onError *string, deadline *time.Duration, max *int) bool {
+	seen := make(map[string]bool)
+	for (onError != nil && p.peekTokenIsKeyword("ON")) || p.peekTokenIsKeyword("DEADLINE") || (max != nil && p.peekTokenIsKeyword("MAX")) {
+		p.nextToken()
+		keyword := strings.ToUpper(p.curToken.Literal)
+		if keyword == "ON" {
+			keyword = "ON ERROR"
+		}
+		if seen[keyword] {
+			p.errorAt(p.curToken.Pos, "%s given more than once %s", keyword, where)
+			return false
+		}
+		seen[keyword] = true
+
+		switch keyword {
+		case "DEADLINE":
+			d, ok := p.parseDuration()
+			if !ok {
+				return false
+			}
+			*deadline = d
+			continue
+		case "MAX":
+			n, ok := p.parseCount("MAX")
+			if !ok {
+				return false
+			}
+			*max = n
+			continue
+		}
+		policy, ok := p.parseErrorPolicy()
+		if !ok {
+			return false
+		}
+		*onError = policy
+	}
+	return true
+}
+
+// parseCount parses the positive whole number following the current token, leaving the current token on it.
+// what names the clause the number belongs to in error messages.
+func (p *Parser) parseCount(what string) (int, bool) {
+	if !p.expectPeek(NUMBER) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(p.curToken.Literal)
+	if err != nil || n < 1 {
+		p.errorAt(p.curToken.Pos, "%s must be a positive whole number, got %s", what, describeToken(p.curToken))
+		return 0, false
+	}
+	return n, true
+}
+
+// parseErrorPolicy parses ON ERROR STOP|CONTINUE starting at ON, leaving the current token on the policy.
+func (p *Parser) parseErrorPolicy() (string, bool) {
+	if !p.expectPeekKeyword("ERROR") {
+		return "", false
+	}
+	if !p.expectPeek(IDENT) {
+		return "", false
+	}
+	policy := strings.ToUpper(p.curToken.Literal)
+	if policy != OnErrorStop && policy != OnErrorContinue {
+		p.errorAt(p.curToken.Pos, "unknown ON ERROR policy '%s'; use STOP or CONTINUE", p.curToken.Literal)
+		return "", false
+	}
+	return policy, true
+}
+
 func (p *Parser) parseData() *Data {
 	data := &Data{}
+	data.Span.Start = p.curToken.Pos
 
 	// Expect DATA_NAME
 	if !p.expectPeek(IDENT) {
@@ -402,7 +577,7 @@ func (p *Parser) parseData() *Data {
 		p.nextToken() // move to 'VALUE' keyword
 		p.nextToken() // move to value
 		if p.curToken.Type != STRING && p.curToken.Type != NUMBER && p.curToken.Type != IDENT {
-			p.errors = append(p.errors, "Expected value after VALUE")
+			p.errorAt(p.curToken.Pos, "expected value after VALUE, got %s", describeToken(p.curToken))
 			return nil
 		}
 		data.InitialValue = p.curToken.Literal
@@ -412,12 +587,15 @@ func (p *Parser) parseData() *Data {
 	if !p.expectPeek(SEMICOL) {
 		return nil
 	}
+	data.Span.End = p.curToken.Pos
 
 	p.nextToken()
 	return data
 }
 
 func (p *Parser) parsePermission() {
+	start := p.curToken.Pos
+
 	// Expect AGENT keyword
 	if !p.expectPeekKeyword("AGENT") {
 		return
@@ -433,8 +611,9 @@ func (p *Parser) parsePermission() {
 	perm, exists := p.parentRequest.Permissions[agentName]
 	if !exists {
 		perm = &Permission{
-			AgentName:      agentName,
+			AgentName:       agentName,
 			DataPermissions: make(map[string][]string),
+			DataSpans:       make(map[string]Span),
 		}
 	}
 
@@ -446,6 +625,7 @@ func (p *Parser) parsePermission() {
 		}
 
 		p.nextToken() // Move to first data name
+		dataStart := p.curToken.Pos
 		dataNames := p.parseIdentifierList()
 
 		// Expect ACCESS keyword
@@ -455,10 +635,14 @@ func (p *Parser) parsePermission() {
 
 		p.nextToken() // Move to first permission
 		permissions := p.parseIdentifierList()
+		dataSpan := Span{Start: dataStart, End: p.curToken.Pos}
 
 		// Assign permissions to each data item
 		for _, dataName := range dataNames {
 			perm.DataPermissions[dataName] = append(perm.DataPermissions[dataName], permissions...)
+			if _, seen := perm.DataSpans[dataName]; !seen {
+				perm.DataSpans[dataName] = dataSpan
+			}
 		}
 
 		// Check if there is another DATA keyword
@@ -469,17 +653,62 @@ func (p *Parser) parsePermission() {
 			p.nextToken() // Consume ';'
 			break
 		} else {
-			p.errors = append(p.errors, "Expected 'DATA' or ';' after permissions")
+			p.errorAt(p.peekToken.Pos, "expected 'DATA' or ';' after permissions, got %s", describeToken(p.peekToken))
 			return
 		}
 	}
 
+	if !exists {
+		perm.Span = Span{Start: start, End: p.curToken.Pos}
+	}
+
 	p.nextToken()
 	p.parentRequest.Permissions[agentName] = perm
 }
 
 func (p *Parser) parseTask() *Task {
+	task := p.parseTaskHeader()
+	if task == nil {
+		return nil
+	}
+
+	// Optional compensation, run to undo the task if the script fails after it completed
+	if p.curTokenIsKeyword("COMPENSATE") {
+		if !p.expectPeekKeyword("WITH") || !p.expectPeekKeyword("TASK") {
+			return nil
+		}
+		task.Compensation = p.parseTaskHeader()
+		if task.Compensation == nil {
+			return nil
+		}
+		if p.curTokenIsKeyword("COMPENSATE") {
+			p.errorAt(p.curToken.Pos, "compensation task %s cannot have its own COMPENSATE", task.Compensation.TaskName)
+			return nil
+		}
+		if task.Compensation.After != nil {
+			p.errorAt(task.Compensation.Span.Start, "compensation task %s cannot have AFTER; it runs when the script fails", task.Compensation.TaskName)
+			return nil
+		}
+	}
+
+	// Expect ';'
+	if !p.curTokenIs(SEMICOL) {
+		p.errorAt(p.curToken.Pos, "expected ';' after TASK %s, got %s", task.TaskName, describeToken(p.curToken))
+		return nil
+	}
+	task.Span.End = p.curToken.Pos
+	if task.Compensation != nil {
+		task.Compensation.Span.End = p.curToken.Pos
+	}
+
+	p.nextToken()
+	return task
+}
+
+// parseTaskHeader parses a task from its TASK keyword through its options, leaving the current token after them.
+func (p *Parser) parseTaskHeader() *Task {
 	task := &Task{}
+	task.Span.Start = p.curToken.Pos
 
 	// Expect TASK_NAME
 	if !p.expectPeek(IDENT) {
@@ -510,24 +739,155 @@ func (p *Parser) parseTask() *Task {
 
 	task.Parameters = p.parseParameters()
 
-	// Expect ';'
-	if !p.curTokenIs(SEMICOL) {
-		p.errors = append(p.errors, "Expected ';' after TASK")
+	// Optional RETRY, BACKOFF, TIMEOUT and AFTER clauses
+	if !p.parseTaskOptions(task) {
 		return nil
 	}
-
-	p.nextToken()
 	return task
 }
 
-func (p *Parser) parseRunSeqBlock() *RunSeqBlock {
-	seqBlock := &RunSeqBlock{
-		Statements: []interface{}{},
+// parseTaskOptions parses the RETRY, BACKOFF, TIMEOUT and AFTER (or DEPENDS ON) clauses starting at the current
+// token, in any order, leaving the current token after the last one.
+func (p *Parser) parseTaskOptions(task *Task) bool {
+	seen := make(map[string]bool)
+	for {
+		keyword := strings.ToUpper(p.curToken.Literal)
+		if p.curToken.Type != IDENT || (keyword != "RETRY" && keyword != "BACKOFF" && keyword != "TIMEOUT" && keyword != "AFTER" && keyword != "DEPENDS") {
+			break
+		}
+		pos := p.curToken.Pos
+		if keyword == "DEPENDS" {
+			if !p.expectPeekKeyword("ON") {
+				return false
+			}
+			keyword = "AFTER"
+		}
+		if seen[keyword] {
+			p.errorAt(pos, "%s given more than once for TASK %s", keyword, task.TaskName)
+			return false
+		}
+		seen[keyword] = true
+
+		switch keyword {
+		case "RETRY":
+			retry, ok := p.parseCount("RETRY")
+			if !ok {
+				return false
+			}
+			task.Retry = retry
+		case "BACKOFF":
+			if !p.expectPeek(IDENT) {
+				return false
+			}
+			strategy := strings.ToLower(p.curToken.Literal)
+			if strategy != BackoffFixed && strategy != BackoffLinear && strategy != BackoffExponential {
+				p.errorAt(p.curToken.Pos, "unknown BACKOFF strategy '%s'; use fixed, linear or exponential", p.curToken.Literal)
+				return false
+			}
+			task.Backoff.Strategy = strategy
+			if p.peekTokenIs(DURATION) {
+				delay, ok := p.parseDuration()
+				if !ok {
+					return false
+				}
+				task.Backoff.Delay = delay
+			}
+		case "TIMEOUT":
+			timeout, ok := p.parseDuration()
+			if !ok {
+				return false
+			}
+			task.Timeout = timeout
+		case "AFTER":
+			after, ok := p.parseNameList()
+			if !ok {
+				return false
+			}
+			task.After = after
+		}
+		p.nextToken()
+	}
+
+	if seen["BACKOFF"] && !seen["RETRY"] {
+		p.errorAt(task.Span.Start, "BACKOFF on TASK %s has no effect without RETRY", task.TaskName)
+		return false
+	}
+	return true
+}
+
+// parseNameList parses a comma-separated list of one or more names after the current token, leaving the
+// current token on the last name.
+func (p *Parser) parseNameList() ([]string, bool) {
+	var names []string
+	for {
+		if !p.expectPeek(IDENT) {
+			return nil, false
+		}
+		names = append(names, p.curToken.Literal)
+		if !p.peekTokenIs(COMMA) {
+			return names, true
+		}
+		p.nextToken()
+	}
+}
+
+// parseStatement parses the task, block, IF or loop statement starting at the current token. Tokens that cannot
+// start a statement are skipped. It returns nil if nothing was parsed.
+func (p *Parser) parseStatement() Statement {
+	start := p.curToken.Pos
+
+	switch {
+	case p.curTokenIsKeyword("TASK"):
+		if task := p.parseTask(); task != nil {
+			return task
+		}
+	case p.curTokenIsKeyword("RUNSEQ"):
+		if seqBlock := p.parseRunSeqBlock(); seqBlock != nil {
+			return seqBlock
+		}
+	case p.curTokenIsKeyword("RUNCON"):
+		if conBlock := p.parseRunConBlock(); conBlock != nil {
+			return conBlock
+		}
+	case p.curTokenIsKeyword("AUTO"):
+		if autoBlock := p.parseAutoBlock(); autoBlock != nil {
+			return autoBlock
+		}
+	case p.curTokenIsKeyword("RUNANY"), p.curTokenIsKeyword("RUNQUORUM"):
+		if anyBlock := p.parseRunAnyBlock(); anyBlock != nil {
+			return anyBlock
+		}
+	case p.curTokenIsKeyword("IF"):
+		if ifBlock := p.parseIfBlock(); ifBlock != nil {
+			return ifBlock
+		}
+	case p.curTokenIsKeyword("FOREACH"):
+		if forEach := p.parseForEachBlock(); forEach != nil {
+			return forEach
+		}
+	case p.curTokenIsKeyword("WHILE"), p.curTokenIsKeyword("UNTIL"):
+		if loop := p.parseLoopBlock(); loop != nil {
+			return loop
+		}
+	case p.curTokenIsKeyword("TRY"):
+		if tryBlock := p.parseTryBlock(); tryBlock != nil {
+			return tryBlock
+		}
 	}
 
+	// Skip the current token if nothing was consumed, so that parsing always makes progress
+	if p.curToken.Pos == start {
+		p.nextToken()
+	}
+	return nil
+}
+
+// parseBlockBody parses statements up to the closing brace of a block opened at start and
+// passes each one to add. It reports false if the block is not closed.
+func (p *Parser) parseBlockBody(keyword string, start Position, add func(Statement)) bool {
 	// Expect '{'
 	if !p.expectPeek(LBRACE) {
-		return nil
+		return false
 	}
 	p.nextToken()
 
@@ -536,92 +896,472 @@ func (p *Parser) parseRunSeqBlock() *RunSeqBlock {
 			p.nextToken()
 			continue
 		}
-		if p.curTokenIsKeyword("TASK") {
-			task := p.parseTask()
-			if task != nil {
-				seqBlock.Statements = append(seqBlock.Statements, task)
-			}
-		} else if p.curTokenIsKeyword("RUNSEQ") {
-			nestedSeq := p.parseRunSeqBlock()
-			if nestedSeq != nil {
-				seqBlock.Statements = append(seqBlock.Statements, nestedSeq)
-			}
-		} else if p.curTokenIsKeyword("RUNCON") {
-			nestedCon := p.parseRunConBlock()
-			if nestedCon != nil {
-				seqBlock.Statements = append(seqBlock.Statements, nestedCon)
-			}
-		} else {
-			p.nextToken()
+		if stmt := p.parseStatement(); stmt != nil {
+			add(stmt)
 		}
 	}
 	if p.curToken.Type != RBRACE {
-		p.errors = append(p.errors, "Expected '}' at the end of RUNSEQ block")
+		p.errorAt(p.curToken.Pos, "expected '}' to close the %s block opened at %s, got %s", keyword, start, describeToken(p.curToken))
+		return false
+	}
+	return true
+}
+
+func (p *Parser) parseRunSeqBlock() *RunSeqBlock {
+	seqBlock := &RunSeqBlock{
+		Statements: []Statement{},
+	}
+	seqBlock.Span.Start = p.curToken.Pos
+
+	if !p.parseRunOptions(fmt.Sprintf("for the RUNSEQ block at %s", seqBlock.Span.Start), &seqBlock.OnError, &seqBlock.Deadline, nil) {
 		return nil
 	}
+
+	ok := p.parseBlockBody("RUNSEQ", seqBlock.Span.Start, func(stmt Statement) {
+		seqBlock.Statements = append(seqBlock.Statements, stmt)
+	})
+	if !ok {
+		return nil
+	}
+	seqBlock.Span.End = p.curToken.Pos
 	p.nextToken()
 	return seqBlock
 }
 
 func (p *Parser) parseRunConBlock() *RunConBlock {
-	conBlock := &RunConBlock{
-		Statements: make(map[string]interface{}),
+	conBlock := &RunConBlock{}
+	conBlock.Span.Start = p.curToken.Pos
+
+	if !p.parseRunOptions(fmt.Sprintf("for the RUNCON block at %s", conBlock.Span.Start), &conBlock.OnError, &conBlock.Deadline, &conBlock.Max) {
+		return nil
 	}
 
-	// Expect '{'
-	if !p.expectPeek(LBRACE) {
+	keys, statements, ok := p.parseKeyedBlockBody("RUNCON", conBlock.Span.Start)
+	if !ok {
+		return nil
+	}
+	conBlock.Keys = keys
+	conBlock.Statements = statements
+	conBlock.Span.End = p.curToken.Pos
+	p.nextToken()
+	return conBlock
+}
+
+// parseAutoBlock parses an AUTO block, whose statements are ordered by the data they read and write.
+func (p *Parser) parseAutoBlock() *AutoBlock {
+	autoBlock := &AutoBlock{}
+	autoBlock.Span.Start = p.curToken.Pos
+
+	if !p.parseRunOptions(fmt.Sprintf("for the AUTO block at %s", autoBlock.Span.Start), &autoBlock.OnError, &autoBlock.Deadline, nil) {
+		return nil
+	}
+
+	keys, statements, ok := p.parseKeyedBlockBody("AUTO", autoBlock.Span.Start)
+	if !ok {
 		return nil
 	}
+	autoBlock.Keys = keys
+	autoBlock.Statements = statements
+	autoBlock.Span.End = p.curToken.Pos
 	p.nextToken()
+	return autoBlock
+}
+
+// parseKeyedBlockBody parses the body of a RUNCON or AUTO block, giving each statement a key that is unique
+// in the block and that AFTER clauses refer to it by. It reports false if the block is not closed or an
+// AFTER clause names no statement of the block; cycles are left to the validator.
+func (p *Parser) parseKeyedBlockBody(keyword string, start Position) ([]string, []Statement, bool) {
+	var keys []string
+	var statements []Statement
 
+	// Nested blocks are numbered in order of appearance, across all block kinds
 	count := 0
+	declared := make(map[string]Statement)
+	ok := p.parseBlockBody(keyword, start, func(stmt Statement) {
+		var key string
+		switch s := stmt.(type) {
+		case *Task:
+			key = s.TaskName
+		case *RunSeqBlock:
+			key = fmt.Sprintf("RUNSEQ_%d", count)
+			count++
+		case *RunConBlock:
+			key = fmt.Sprintf("RUNCON_%d", count)
+			count++
+		case *IfBlock:
+			key = fmt.Sprintf("IF_%d", count)
+			count++
+		case *ForEachBlock:
+			key = fmt.Sprintf("FOREACH_%d", count)
+			count++
+		case *LoopBlock:
+			key = fmt.Sprintf("%s_%d", s.Keyword(), count)
+			count++
+		case *TryBlock:
+			key = fmt.Sprintf("TRY_%d", count)
+			count++
+		case *RunAnyBlock:
+			key = fmt.Sprintf("%s_%d", s.Keyword(), count)
+			count++
+		case *AutoBlock:
+			key = fmt.Sprintf("AUTO_%d", count)
+			count++
+		}
 
-	for p.curToken.Type != RBRACE && p.curToken.Type != EOF {
-		if p.curToken.Type == COMMENT {
-			p.nextToken()
+		// Block keys are generated, so a task can be named like one
+		pos := stmt.GetSpan().Start
+		if first, exists := declared[key]; exists {
+			_, firstIsTask := first.(*Task)
+			_, isTask := stmt.(*Task)
+			switch {
+			case firstIsTask && isTask:
+				p.errorAt(pos, "duplicate task name '%s' in %s block, first declared at %s", key, keyword, first.GetSpan().Start)
+			case isTask:
+				p.errorAt(pos, "task name '%s' in %s block is already the key of the block at %s", key, keyword, first.GetSpan().Start)
+			default:
+				p.errorAt(pos, "key '%s' generated for this block in %s block is already the name of the task at %s", key, keyword, first.GetSpan().Start)
+			}
+			return
+		}
+		declared[key] = stmt
+		keys = append(keys, key)
+		statements = append(statements, stmt)
+	})
+	if !ok {
+		return nil, nil, false
+	}
+
+	// Tasks can only wait for other statements of the same block
+	for i, stmt := range statements {
+		t, isTask := stmt.(*Task)
+		if !isTask {
 			continue
 		}
-		if p.curTokenIsKeyword("TASK") {
-			task := p.parseTask()
-			if task != nil {
-				conBlock.Statements[task.TaskName] = task
-			}
-		} else if p.curTokenIsKeyword("RUNSEQ") {
-			nestedSeq := p.parseRunSeqBlock()
-			if nestedSeq != nil {
-				key := fmt.Sprintf("RUNSEQ_%d", count)
-				conBlock.Statements[key] = nestedSeq
-				count++
+		for _, dependency := range t.After {
+			if dependency == keys[i] {
+				p.errorAt(t.Span.Start, "TASK %s cannot run AFTER itself", t.TaskName)
+				return nil, nil, false
 			}
-		} else if p.curTokenIsKeyword("RUNCON") {
-			nestedCon := p.parseRunConBlock()
-			if nestedCon != nil {
-				key := fmt.Sprintf("RUNCON_%d", count)
-				conBlock.Statements[key] = nestedCon
-				count++
+			if _, exists := declared[dependency]; !exists {
+				p.errorAt(t.Span.Start, "TASK %s runs AFTER '%s', which is not in the %s block opened at %s", t.TaskName, dependency, keyword, start)
+				return nil, nil, false
 			}
-		} else {
-			p.nextToken()
 		}
 	}
-	if p.curToken.Type != RBRACE {
-		p.errors = append(p.errors, "Expected '}' at the end of RUNCON block")
+	return keys, statements, true
+}
+
+// parseRunAnyBlock parses RUNANY or RUNQUORUM n, with an optional DEADLINE, and the block that follows.
+func (p *Parser) parseRunAnyBlock() *RunAnyBlock {
+	anyBlock := &RunAnyBlock{Any: p.curTokenIsKeyword("RUNANY"), Quorum: 1}
+	anyBlock.Span.Start = p.curToken.Pos
+	keyword := anyBlock.Keyword()
+
+	if !anyBlock.Any {
+		quorum, ok := p.parseCount("RUNQUORUM")
+		if !ok {
+			return nil
+		}
+		anyBlock.Quorum = quorum
+	}
+	if !p.parseRunOptions(fmt.Sprintf("for the %s block at %s", keyword, anyBlock.Span.Start), nil, &anyBlock.Deadline, nil) {
+		return nil
+	}
+
+	ok := p.parseBlockBody(keyword, anyBlock.Span.Start, func(stmt Statement) {
+		anyBlock.Statements = append(anyBlock.Statements, stmt)
+	})
+	if !ok {
+		return nil
+	}
+	if len(anyBlock.Statements) == 0 {
+		p.errorAt(anyBlock.Span.Start, "%s needs at least one statement in its block", keyword)
 		return nil
 	}
+	if len(anyBlock.Statements) < anyBlock.Quorum {
+		p.errorAt(anyBlock.Span.Start, "RUNQUORUM %d needs at least %d statements in its block, got %d", anyBlock.Quorum, anyBlock.Quorum, len(anyBlock.Statements))
+		return nil
+	}
+	anyBlock.Span.End = p.curToken.Pos
 	p.nextToken()
-	return conBlock
+	return anyBlock
+}
+
+// parseIfBlock parses an IF statement with its optional ELSE or ELSE IF branch.
+func (p *Parser) parseIfBlock() *IfBlock {
+	ifBlock := &IfBlock{
+		Then: []Statement{},
+	}
+	ifBlock.Span.Start = p.curToken.Pos
+
+	ifBlock.Condition = p.parseCondition()
+	if ifBlock.Condition == nil {
+		return nil
+	}
+
+	ok := p.parseBlockBody("IF", ifBlock.Span.Start, func(stmt Statement) {
+		ifBlock.Then = append(ifBlock.Then, stmt)
+	})
+	if !ok {
+		return nil
+	}
+	ifBlock.Span.End = p.curToken.Pos
+
+	if !p.peekTokenIsKeyword("ELSE") {
+		p.nextToken()
+		return ifBlock
+	}
+	p.nextToken() // Move to 'ELSE'
+	elseStart := p.curToken.Pos
+
+	// ELSE IF chains nest the next IF as the only statement of the ELSE branch
+	if p.peekTokenIsKeyword("IF") {
+		p.nextToken()
+		elseIf := p.parseIfBlock()
+		if elseIf == nil {
+			return nil
+		}
+		ifBlock.Else = []Statement{elseIf}
+		ifBlock.Span.End = elseIf.Span.End
+		return ifBlock
+	}
+
+	ifBlock.Else = []Statement{}
+	ok = p.parseBlockBody("ELSE", elseStart, func(stmt Statement) {
+		ifBlock.Else = append(ifBlock.Else, stmt)
+	})
+	if !ok {
+		return nil
+	}
+	ifBlock.Span.End = p.curToken.Pos
+	p.nextToken()
+	return ifBlock
+}
+
+// parseTryBlock parses TRY { ... } [CATCH { ... }] [FINALLY { ... }].
+func (p *Parser) parseTryBlock() *TryBlock {
+	tryBlock := &TryBlock{
+		Body: []Statement{},
+	}
+	tryBlock.Span.Start = p.curToken.Pos
+
+	ok := p.parseBlockBody("TRY", tryBlock.Span.Start, func(stmt Statement) {
+		tryBlock.Body = append(tryBlock.Body, stmt)
+	})
+	if !ok {
+		return nil
+	}
+	tryBlock.Span.End = p.curToken.Pos
+
+	if p.peekTokenIsKeyword("CATCH") {
+		p.nextToken()
+		tryBlock.Catch = []Statement{}
+		ok = p.parseBlockBody("CATCH", p.curToken.Pos, func(stmt Statement) {
+			tryBlock.Catch = append(tryBlock.Catch, stmt)
+		})
+		if !ok {
+			return nil
+		}
+		tryBlock.Span.End = p.curToken.Pos
+	}
+
+	if p.peekTokenIsKeyword("FINALLY") {
+		p.nextToken()
+		tryBlock.Finally = []Statement{}
+		ok = p.parseBlockBody("FINALLY", p.curToken.Pos, func(stmt Statement) {
+			tryBlock.Finally = append(tryBlock.Finally, stmt)
+		})
+		if !ok {
+			return nil
+		}
+		tryBlock.Span.End = p.curToken.Pos
+	}
+
+	if tryBlock.Catch == nil && tryBlock.Finally == nil {
+		p.errorAt(p.peekToken.Pos, "expected CATCH or FINALLY after the TRY block opened at %s, got %s", tryBlock.Span.Start, describeToken(p.peekToken))
+		return nil
+	}
+	p.nextToken()
+	return tryBlock
+}
+
+// parseForEachBlock parses FOREACH item IN list [CONCURRENT] [COLLECT var INTO output] { ... }.
+func (p *Parser) parseForEachBlock() *ForEachBlock {
+	forEach := &ForEachBlock{
+		Body: []Statement{},
+	}
+	forEach.Span.Start = p.curToken.Pos
+
+	// Expect the loop variable
+	if !p.expectPeek(IDENT) {
+		return nil
+	}
+	forEach.Item = p.curToken.Literal
+
+	// Expect IN and the list
+	if !p.expectPeekKeyword("IN") {
+		return nil
+	}
+	if !p.expectPeek(IDENT) {
+		return nil
+	}
+	forEach.List = p.curToken.Literal
+
+	if p.peekTokenIsKeyword("CONCURRENT") {
+		p.nextToken()
+		forEach.Concurrent = true
+	}
+
+	if p.peekTokenIsKeyword("COLLECT") {
+		p.nextToken()
+		if !p.expectPeek(IDENT) {
+			return nil
+		}
+		forEach.CollectVar = p.curToken.Literal
+		if !p.expectPeekKeyword("INTO") {
+			return nil
+		}
+		if !p.expectPeek(IDENT) {
+			return nil
+		}
+		forEach.CollectInto = p.curToken.Literal
+	}
+
+	ok := p.parseBlockBody("FOREACH", forEach.Span.Start, func(stmt Statement) {
+		forEach.Body = append(forEach.Body, stmt)
+	})
+	if !ok {
+		return nil
+	}
+	forEach.Span.End = p.curToken.Pos
+	p.nextToken()
+	return forEach
+}
+
+// parseLoopBlock parses WHILE|UNTIL condition MAX n [EVERY duration] { ... }.
+func (p *Parser) parseLoopBlock() *LoopBlock {
+	loop := &LoopBlock{
+		Until: p.curTokenIsKeyword("UNTIL"),
+		Body:  []Statement{},
+	}
+	loop.Span.Start = p.curToken.Pos
+
+	loop.Condition = p.parseCondition()
+	if loop.Condition == nil {
+		return nil
+	}
+
+	// Every loop must be bounded
+	if !p.expectPeekKeyword("MAX") {
+		return nil
+	}
+	max, ok := p.parseCount("MAX")
+	if !ok {
+		return nil
+	}
+	loop.Max = max
+
+	if p.peekTokenIsKeyword("EVERY") {
+		p.nextToken()
+		every, ok := p.parseDuration()
+		if !ok {
+			return nil
+		}
+		loop.Every = every
+	}
+
+	ok = p.parseBlockBody(loop.Keyword(), loop.Span.Start, func(stmt Statement) {
+		loop.Body = append(loop.Body, stmt)
+	})
+	if !ok {
+		return nil
+	}
+	loop.Span.End = p.curToken.Pos
+	p.nextToken()
+	return loop
+}
+
+// parseDuration parses the duration after the current token.
+func (p *Parser) parseDuration() (time.Duration, bool) {
+	if !p.expectPeek(DURATION) {
+		return 0, false
+	}
+	d, err := time.ParseDuration(p.curToken.Literal)
+	if err != nil || d <= 0 {
+		p.errorAt(p.curToken.Pos, "invalid duration '%s'; use a positive number with a unit such as 500ms, 10s or 1m", p.curToken.Literal)
+		return 0, false
+	}
+	return d, true
+}
+
+// parseCondition parses the condition following IF, leaving the current token on its last token.
+func (p *Parser) parseCondition() *Condition {
+	p.nextToken() // Move to the left operand
+	cond := &Condition{}
+	cond.Span.Start = p.curToken.Pos
+
+	left, ok := p.parseOperand()
+	if !ok {
+		return nil
+	}
+	cond.Left = left
+
+	switch {
+	case p.peekTokenIsKeyword("IS"):
+		p.nextToken()
+		cond.Operator = OpIsEmpty
+		if p.peekTokenIsKeyword("NOT") {
+			p.nextToken()
+			cond.Operator = OpIsNotEmpty
+		}
+		if !p.expectPeekKeyword("EMPTY") {
+			return nil
+		}
+	case p.peekTokenIsKeyword("CONTAINS"):
+		p.nextToken()
+		cond.Operator = OpContains
+	default:
+		op, isComparison := comparisonOperators[p.peekToken.Type]
+		if !isComparison {
+			p.errorAt(p.peekToken.Pos, "expected a comparison, 'IS' or 'CONTAINS' in condition, got %s", describeToken(p.peekToken))
+			return nil
+		}
+		p.nextToken()
+		cond.Operator = op
+	}
+
+	if cond.Operator != OpIsEmpty && cond.Operator != OpIsNotEmpty {
+		p.nextToken() // Move to the right operand
+		right, ok := p.parseOperand()
+		if !ok {
+			return nil
+		}
+		cond.Right = right
+	}
+
+	cond.Span.End = p.curToken.Pos
+	return cond
+}
+
+// parseOperand parses the current token as a literal or a reference to global data.
+func (p *Parser) parseOperand() (Parameter, bool) {
+	if p.curToken.Type != STRING && p.curToken.Type != NUMBER && p.curToken.Type != IDENT {
+		p.errorAt(p.curToken.Pos, "expected a value in condition, got %s", describeToken(p.curToken))
+		return Parameter{}, false
+	}
+	return Parameter{Value: p.curToken.Literal, Type: p.curToken.Type}, true
 }
 
 // Helper functions
 
-func (p *Parser) parseParameters() map[string]string {
-	params := make(map[string]string)
+func (p *Parser) parseParameters() map[string]Parameter {
+	params := make(map[string]Parameter)
 
 	p.nextToken() // move to first parameter or ')'
 
 	for p.curToken.Type != RPAREN && p.curToken.Type != EOF {
 		if p.curToken.Type != IDENT {
-			p.errors = append(p.errors, fmt.Sprintf("Expected parameter name, got '%s'", p.curToken.Literal))
+			p.errorAt(p.curToken.Pos, "expected parameter name, got %s", describeToken(p.curToken))
 			return nil
 		}
 		key := p.curToken.Literal
@@ -632,11 +1372,10 @@ func (p *Parser) parseParameters() map[string]string {
 
 		p.nextToken() // move to value
 		if p.curToken.Type != STRING && p.curToken.Type != NUMBER && p.curToken.Type != IDENT {
-			p.errors = append(p.errors, fmt.Sprintf("Expected parameter value, got '%s'", p.curToken.Literal))
+			p.errorAt(p.curToken.Pos, "expected value for parameter '%s', got %s", key, describeToken(p.curToken))
 			return nil
 		}
-		value := p.curToken.Literal
-		params[key] = value
+		params[key] = Parameter{Value: p.curToken.Literal, Type: p.curToken.Type}
 
 		if p.peekTokenIs(COMMA) {
 			p.nextToken() // consume ','
@@ -645,13 +1384,13 @@ func (p *Parser) parseParameters() map[string]string {
 			p.nextToken() // consume ')'
 			break
 		} else {
-			p.errors = append(p.errors, "Expected ',' or ')' in parameters")
+			p.errorAt(p.peekToken.Pos, "expected ',' or ')' in parameters, got %s", describeToken(p.peekToken))
 			return nil
 		}
 	}
 
 	if p.curToken.Type != RPAREN {
-		p.errors = append(p.errors, "Expected ')' at the end of parameters")
+		p.errorAt(p.curToken.Pos, "expected ')' at the end of parameters, got %s", describeToken(p.curToken))
 		return nil
 	}
 
@@ -663,7 +1402,7 @@ func (p *Parser) parseIdentifierList() []string {
 	identifiers := []string{}
 
 	if p.curToken.Type != IDENT {
-		p.errors = append(p.errors, "Expected identifier")
+		p.errorAt(p.curToken.Pos, "expected identifier, got %s", describeToken(p.curToken))
 		return nil
 	}
 
@@ -673,7 +1412,7 @@ func (p *Parser) parseIdentifierList() []string {
 		p.nextToken() // consume ','
 		p.nextToken() // move to next identifier
 		if p.curToken.Type != IDENT {
-			p.errors = append(p.errors, "Expected identifier")
+			p.errorAt(p.curToken.Pos, "expected identifier after ',', got %s", describeToken(p.curToken))
 			return nil
 		}
 		identifiers = append(identifiers, p.curToken.Literal)
@@ -695,11 +1434,11 @@ func (p *Parser) expectPeek(t TokenType) bool {
 }
 
 func (p *Parser) peekErrorToken(t TokenType) {
-	msg := fmt.Sprintf("Expected next token to be %v, got %v instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.peekToken.Pos, "expected %s, got %s", t, describeToken(p.peekToken))
 }
 
-func (p *Parser) Errors() []string {
+// Errors returns every parse error in the order it was found.
+func (p *Parser) Errors() []ParseError {
 	return p.errors
 }
 
@@ -759,21 +1498,58 @@ END
 }
 
 // Helper function to print statements.
-func printStatements(statements []interface{}, indent int) {
+func printStatements(statements []Statement, indent int) {
 	prefix := strings.Repeat("    ", indent)
 	for _, stmt := range statements {
 		switch s := stmt.(type) {
 		case *Task:
-			fmt.Printf("%sTask: %s, Agent: %s, Parameters: %v\n", prefix, s.TaskName, s.AgentName, s.Parameters)
+			fmt.Printf("%sTask: %s, Agent: %s, Parameters: %v, Retry: %d, Backoff: %s %s, Timeout: %s, After: %v\n", prefix, s.TaskName, s.AgentName, s.Parameters, s.Retry, s.Backoff.Strategy, s.Backoff.Delay, s.Timeout, s.After)
+			if s.Compensation != nil {
+				fmt.Printf("%s    Compensate with:\n", prefix)
+				printStatements([]Statement{s.Compensation}, indent+2)
+			}
 		case *RunSeqBlock:
-			fmt.Printf("%sRunSeqBlock:\n", prefix)
+			fmt.Printf("%sRunSeqBlock: OnError: %s, Deadline: %s\n", prefix, s.OnError, s.Deadline)
 			printStatements(s.Statements, indent+1)
 		case *RunConBlock:
-			fmt.Printf("%sRunConBlock:\n", prefix)
-			for key, conStmt := range s.Statements {
-				fmt.Printf("%s    Key: %s\n", prefix, key)
-				printStatements([]interface{}{conStmt}, indent+2)
+			fmt.Printf("%sRunConBlock: OnError: %s, Deadline: %s\n", prefix, s.OnError, s.Deadline)
+			for i, conStmt := range s.Statements {
+				fmt.Printf("%s    Key: %s\n", prefix, s.Keys[i])
+				printStatements([]Statement{conStmt}, indent+2)
+			}
+		case *AutoBlock:
+			fmt.Printf("%sAutoBlock: OnError: %s, Deadline: %s\n", prefix, s.OnError, s.Deadline)
+			for i, autoStmt := range s.Statements {
+				fmt.Printf("%s    Key: %s\n", prefix, s.Keys[i])
+				printStatements([]Statement{autoStmt}, indent+2)
+			}
+		case *RunAnyBlock:
+			fmt.Printf("%sRunAnyBlock: %s, Quorum: %d, Deadline: %s\n", prefix, s.Keyword(), s.Quorum, s.Deadline)
+			printStatements(s.Statements, indent+1)
+		case *IfBlock:
+			fmt.Printf("%sIfBlock: %s\n", prefix, s.Condition)
+			printStatements(s.Then, indent+1)
+			if s.Else != nil {
+				fmt.Printf("%sElse:\n", prefix)
+				printStatements(s.Else, indent+1)
+			}
+		case *LoopBlock:
+			fmt.Printf("%sLoopBlock: %s %s, Max: %d, Every: %s\n", prefix, s.Keyword(), s.Condition, s.Max, s.Every)
+			printStatements(s.Body, indent+1)
+		case *TryBlock:
+			fmt.Printf("%sTryBlock:\n", prefix)
+			printStatements(s.Body, indent+1)
+			if s.Catch != nil {
+				fmt.Printf("%sCatch:\n", prefix)
+				printStatements(s.Catch, indent+1)
+			}
+			if s.Finally != nil {
+				fmt.Printf("%sFinally:\n", prefix)
+				printStatements(s.Finally, indent+1)
 			}
+		case *ForEachBlock:
+			fmt.Printf("%sForEachBlock: %s IN %s, Concurrent: %v, Collect: %s INTO %s\n", prefix, s.Item, s.List, s.Concurrent, s.CollectVar, s.CollectInto)
+			printStatements(s.Body, indent+1)
 		default:
 			fmt.Printf("%sUnknown statement type\n", prefix)
 		}
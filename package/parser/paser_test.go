@@ -73,32 +73,33 @@ END
 						},
 					},
 				},
-				Statements: []interface{}{
+				Statements: []Statement{
 					&RunSeqBlock{
-						Statements: []interface{}{
+						Statements: []Statement{
 							&Task{
 								TaskName:  "FetchData",
 								AgentName: "Agent1",
-								Parameters: map[string]string{
-									"source": "DB",
-									"output": "data1",
+								Parameters: map[string]Parameter{
+									"source": {Value: "DB", Type: STRING},
+									"output": {Value: "data1", Type: IDENT},
 								},
 							},
 							&RunConBlock{
-								Statements: map[string]interface{}{
-									"ProcessData": &Task{
+								Keys: []string{"ProcessData", "LogData"},
+								Statements: []Statement{
+									&Task{
 										TaskName:  "ProcessData",
 										AgentName: "Agent2",
-										Parameters: map[string]string{
-											"input":  "data1",
-											"output": "data2",
+										Parameters: map[string]Parameter{
+											"input":  {Value: "data1", Type: IDENT},
+											"output": {Value: "data2", Type: IDENT},
 										},
 									},
-									"LogData": &Task{
+									&Task{
 										TaskName:  "LogData",
 										AgentName: "Agent3",
-										Parameters: map[string]string{
-											"input": "data1",
+										Parameters: map[string]Parameter{
+											"input": {Value: "data1", Type: IDENT},
 										},
 									},
 								},
@@ -106,8 +107,8 @@ END
 							&Task{
 								TaskName:  "SaveData",
 								AgentName: "Agent4",
-								Parameters: map[string]string{
-									"input": "data2",
+								Parameters: map[string]Parameter{
+									"input": {Value: "data2", Type: IDENT},
 								},
 							},
 						},
@@ -164,32 +165,33 @@ END
 						},
 					},
 				},
-				Statements: []interface{}{
+				Statements: []Statement{
 					&RunSeqBlock{
-						Statements: []interface{}{
+						Statements: []Statement{
 							&Task{
 								TaskName:  "Setup",
 								AgentName: "Worker",
-								Parameters: map[string]string{
-									"config": "config",
+								Parameters: map[string]Parameter{
+									"config": {Value: "config", Type: IDENT},
 								},
 							},
 							&RunConBlock{
-								Statements: map[string]interface{}{
-									"Compute1": &Task{
+								Keys: []string{"Compute1", "Compute2"},
+								Statements: []Statement{
+									&Task{
 										TaskName:  "Compute1",
 										AgentName: "Worker",
-										Parameters: map[string]string{
-											"input":  "config",
-											"output": "results",
+										Parameters: map[string]Parameter{
+											"input":  {Value: "config", Type: IDENT},
+											"output": {Value: "results", Type: IDENT},
 										},
 									},
-									"Compute2": &Task{
+									&Task{
 										TaskName:  "Compute2",
 										AgentName: "Worker",
-										Parameters: map[string]string{
-											"input":  "config",
-											"output": "results",
+										Parameters: map[string]Parameter{
+											"input":  {Value: "config", Type: IDENT},
+											"output": {Value: "results", Type: IDENT},
 										},
 									},
 								},
@@ -197,8 +199,8 @@ END
 							&Task{
 								TaskName:  "Report",
 								AgentName: "Reporter",
-								Parameters: map[string]string{
-									"data": "results",
+								Parameters: map[string]Parameter{
+									"data": {Value: "results", Type: IDENT},
 								},
 							},
 						},
@@ -276,52 +278,53 @@ END
 						},
 					},
 				},
-				Statements: []interface{}{
+				Statements: []Statement{
 					&RunSeqBlock{
-						Statements: []interface{}{
+						Statements: []Statement{
 							&Task{
 								TaskName:  "Initialize",
 								AgentName: "MainAgent",
-								Parameters: map[string]string{
-									"output": "sharedData",
+								Parameters: map[string]Parameter{
+									"output": {Value: "sharedData", Type: IDENT},
 								},
 							},
 							&RunConBlock{
-								Statements: map[string]interface{}{
-									"RUNSEQ_0": &RunSeqBlock{
-										Statements: []interface{}{
+								Keys: []string{"RUNSEQ_0", "RUNSEQ_1"},
+								Statements: []Statement{
+									&RunSeqBlock{
+										Statements: []Statement{
 											&Task{
 												TaskName:  "Process1",
 												AgentName: "Worker1",
-												Parameters: map[string]string{
-													"input":  "sharedData",
-													"output": "tempData1",
+												Parameters: map[string]Parameter{
+													"input":  {Value: "sharedData", Type: IDENT},
+													"output": {Value: "tempData1", Type: IDENT},
 												},
 											},
 											&Task{
 												TaskName:  "Finalize1",
 												AgentName: "Worker1",
-												Parameters: map[string]string{
-													"input": "tempData1",
+												Parameters: map[string]Parameter{
+													"input": {Value: "tempData1", Type: IDENT},
 												},
 											},
 										},
 									},
-									"RUNSEQ_1": &RunSeqBlock{
-										Statements: []interface{}{
+									&RunSeqBlock{
+										Statements: []Statement{
 											&Task{
 												TaskName:  "Process2",
 												AgentName: "Worker2",
-												Parameters: map[string]string{
-													"input":  "sharedData",
-													"output": "tempData2",
+												Parameters: map[string]Parameter{
+													"input":  {Value: "sharedData", Type: IDENT},
+													"output": {Value: "tempData2", Type: IDENT},
 												},
 											},
 											&Task{
 												TaskName:  "Finalize2",
 												AgentName: "Worker2",
-												Parameters: map[string]string{
-													"input": "tempData2",
+												Parameters: map[string]Parameter{
+													"input": {Value: "tempData2", Type: IDENT},
 												},
 											},
 										},
@@ -331,9 +334,9 @@ END
 							&Task{
 								TaskName:  "Aggregate",
 								AgentName: "MainAgent",
-								Parameters: map[string]string{
-									"input1": "tempData1",
-									"input2": "tempData2",
+								Parameters: map[string]Parameter{
+									"input1": {Value: "tempData1", Type: IDENT},
+									"input2": {Value: "tempData2", Type: IDENT},
 								},
 							},
 						},
@@ -404,37 +407,37 @@ END
 						},
 					},
 				},
-				Statements: []interface{}{
+				Statements: []Statement{
 					&RunSeqBlock{
-						Statements: []interface{}{
+						Statements: []Statement{
 							&Task{
 								TaskName:  "CollectData",
 								AgentName: "DataCollector",
-								Parameters: map[string]string{
-									"output": "userProfiles",
+								Parameters: map[string]Parameter{
+									"output": {Value: "userProfiles", Type: IDENT},
 								},
 							},
 							&Task{
 								TaskName:  "ProcessData",
 								AgentName: "DataProcessor",
-								Parameters: map[string]string{
-									"input":  "userProfiles",
-									"output": "processedData",
+								Parameters: map[string]Parameter{
+									"input":  {Value: "userProfiles", Type: IDENT},
+									"output": {Value: "processedData", Type: IDENT},
 								},
 							},
 							&Task{
 								TaskName:  "Analyze",
 								AgentName: "Analyst",
-								Parameters: map[string]string{
-									"input":  "processedData",
-									"output": "finalReport",
+								Parameters: map[string]Parameter{
+									"input":  {Value: "processedData", Type: IDENT},
+									"output": {Value: "finalReport", Type: IDENT},
 								},
 							},
 							&Task{
 								TaskName:  "Publish",
 								AgentName: "Analyst",
-								Parameters: map[string]string{
-									"report": "finalReport",
+								Parameters: map[string]Parameter{
+									"report": {Value: "finalReport", Type: IDENT},
 								},
 							},
 						},
@@ -510,34 +513,35 @@ END
 						},
 					},
 				},
-				Statements: []interface{}{
+				Statements: []Statement{
 					&RunSeqBlock{
-						Statements: []interface{}{
+						Statements: []Statement{
 							&Task{
 								TaskName:  "StartProcess",
 								AgentName: "Starter",
-								Parameters: map[string]string{
-									"output": "initialData",
+								Parameters: map[string]Parameter{
+									"output": {Value: "initialData", Type: IDENT},
 								},
 							},
 							&RunSeqBlock{
-								Statements: []interface{}{
+								Statements: []Statement{
 									&RunConBlock{
-										Statements: map[string]interface{}{
-											"IntermediateStep1": &Task{
+										Keys: []string{"IntermediateStep1", "IntermediateStep2"},
+										Statements: []Statement{
+											&Task{
 												TaskName:  "IntermediateStep1",
 												AgentName: "MiddleMan",
-												Parameters: map[string]string{
-													"input":  "initialData",
-													"output": "intermediateData",
+												Parameters: map[string]Parameter{
+													"input":  {Value: "initialData", Type: IDENT},
+													"output": {Value: "intermediateData", Type: IDENT},
 												},
 											},
-											"IntermediateStep2": &Task{
+											&Task{
 												TaskName:  "IntermediateStep2",
 												AgentName: "MiddleMan",
-												Parameters: map[string]string{
-													"input":  "initialData",
-													"output": "intermediateData",
+												Parameters: map[string]Parameter{
+													"input":  {Value: "initialData", Type: IDENT},
+													"output": {Value: "intermediateData", Type: IDENT},
 												},
 											},
 										},
@@ -545,8 +549,8 @@ END
 									&Task{
 										TaskName:  "MergeData",
 										AgentName: "MiddleMan",
-										Parameters: map[string]string{
-											"input": "intermediateData",
+										Parameters: map[string]Parameter{
+											"input": {Value: "intermediateData", Type: IDENT},
 										},
 									},
 								},
@@ -554,9 +558,9 @@ END
 							&Task{
 								TaskName:  "Finalize",
 								AgentName: "Finisher",
-								Parameters: map[string]string{
-									"input":  "intermediateData",
-									"output": "finalData",
+								Parameters: map[string]Parameter{
+									"input":  {Value: "intermediateData", Type: IDENT},
+									"output": {Value: "finalData", Type: IDENT},
 								},
 							},
 						},
@@ -642,38 +646,38 @@ END
 						},
 					},
 				},
-				Statements: []interface{}{
+				Statements: []Statement{
 					&RunSeqBlock{
-						Statements: []interface{}{
+						Statements: []Statement{
 							&Task{
 								TaskName:  "ScrapeData",
 								AgentName: "Scraper",
-								Parameters: map[string]string{
-									"output": "rawData",
+								Parameters: map[string]Parameter{
+									"output": {Value: "rawData", Type: IDENT},
 								},
 							},
 							&Task{
 								TaskName:  "CleanData",
 								AgentName: "Cleaner",
-								Parameters: map[string]string{
-									"input":  "rawData",
-									"output": "cleanedData",
+								Parameters: map[string]Parameter{
+									"input":  {Value: "rawData", Type: IDENT},
+									"output": {Value: "cleanedData", Type: IDENT},
 								},
 							},
 							&Task{
 								TaskName:  "AnalyzeData",
 								AgentName: "Analyst",
-								Parameters: map[string]string{
-									"input":  "cleanedData",
-									"output": "analyzedData",
+								Parameters: map[string]Parameter{
+									"input":  {Value: "cleanedData", Type: IDENT},
+									"output": {Value: "analyzedData", Type: IDENT},
 								},
 							},
 							&Task{
 								TaskName:  "GenerateReport",
 								AgentName: "Reporter",
-								Parameters: map[string]string{
-									"input":  "analyzedData",
-									"output": "report",
+								Parameters: map[string]Parameter{
+									"input":  {Value: "analyzedData", Type: IDENT},
+									"output": {Value: "report", Type: IDENT},
 								},
 							},
 						},
@@ -688,6 +692,7 @@ END
 		l := NewLexer(test.input)
 		p := NewParser(l)
 		actual := p.ParseProgram()
+		clearSpans(actual)
 
 		if len(p.Errors()) != 0 {
 			t.Errorf("Parser errors in test '%s': %v", test.name, p.Errors())
@@ -698,3 +703,254 @@ END
 		}
 	}
 }
+
+// clearSpans zeroes every source position so parsed structures can be compared with hand-written ones.
+func clearSpans(pr *ParentRequest) {
+	for _, data := range pr.GlobalData {
+		data.Span = Span{}
+	}
+	for _, perm := range pr.Permissions {
+		perm.Span = Span{}
+		perm.DataSpans = nil
+	}
+	clearStatementSpans(pr.Statements)
+}
+
+func clearStatementSpans(statements []Statement) {
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case *Task:
+			s.Span = Span{}
+			if s.Compensation != nil {
+				s.Compensation.Span = Span{}
+			}
+		case *RunSeqBlock:
+			s.Span = Span{}
+			clearStatementSpans(s.Statements)
+		case *RunConBlock:
+			s.Span = Span{}
+			clearStatementSpans(s.Statements)
+		case *RunAnyBlock:
+			s.Span = Span{}
+			clearStatementSpans(s.Statements)
+		case *AutoBlock:
+			s.Span = Span{}
+			clearStatementSpans(s.Statements)
+		case *IfBlock:
+			s.Span = Span{}
+			s.Condition.Span = Span{}
+			clearStatementSpans(s.Then)
+			clearStatementSpans(s.Else)
+		case *ForEachBlock:
+			s.Span = Span{}
+			clearStatementSpans(s.Body)
+		case *LoopBlock:
+			s.Span = Span{}
+			s.Condition.Span = Span{}
+			clearStatementSpans(s.Body)
+		case *TryBlock:
+			s.Span = Span{}
+			clearStatementSpans(s.Body)
+			clearStatementSpans(s.Catch)
+			clearStatementSpans(s.Finally)
+		}
+	}
+}
+
+// TestLexerPositions tests that tokens carry the line and column they start at.
+func TestLexerPositions(t *testing.T) {
+	input := "START\n  DATA x TYPE String ;\n// comment\n\tEND"
+	expected := []Token{
+		{Type: IDENT, Literal: "START", Pos: Position{Line: 1, Column: 1}},
+		{Type: IDENT, Literal: "DATA", Pos: Position{Line: 2, Column: 3}},
+		{Type: IDENT, Literal: "x", Pos: Position{Line: 2, Column: 8}},
+		{Type: IDENT, Literal: "TYPE", Pos: Position{Line: 2, Column: 10}},
+		{Type: IDENT, Literal: "String", Pos: Position{Line: 2, Column: 15}},
+		{Type: SEMICOL, Literal: ";", Pos: Position{Line: 2, Column: 22}},
+		{Type: COMMENT, Literal: " comment", Pos: Position{Line: 3, Column: 1}},
+		{Type: IDENT, Literal: "END", Pos: Position{Line: 4, Column: 2}},
+		{Type: EOF, Literal: "", Pos: Position{Line: 4, Column: 5}},
+	}
+
+	l := NewLexer(input)
+	for i, want := range expected {
+		got := l.NextToken()
+		if got != want {
+			t.Errorf("Token %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+// TestNodeSpans tests that tasks, data, permissions and blocks record where they appear.
+func TestNodeSpans(t *testing.T) {
+	input := `START
+DATA flightInfo TYPE String ;
+PERM AGENT FlightGetter DATA origin ACCESS READ ;
+PERM AGENT FlightGetter DATA flightInfo ACCESS WRITE ;
+RUNSEQ {
+    TASK ScheduleFlight AGENT FlightGetter PARAMETERS (OUTPUT=flightInfo) ;
+    RUNCON {
+        TASK CheckWeather AGENT WeatherChecker PARAMETERS () ;
+    }
+}
+END`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	if got, want := pr.GlobalData["flightInfo"].Span, (Span{Start: Position{2, 1}, End: Position{2, 29}}); got != want {
+		t.Errorf("Data span: expected %+v, got %+v", want, got)
+	}
+
+	perm := pr.Permissions["FlightGetter"]
+	if got, want := perm.Span, (Span{Start: Position{3, 1}, End: Position{3, 49}}); got != want {
+		t.Errorf("Permission span: expected %+v, got %+v", want, got)
+	}
+	if got, want := perm.DataSpans["flightInfo"].Start, (Position{4, 30}); got != want {
+		t.Errorf("Permission data span: expected %+v, got %+v", want, got)
+	}
+
+	seq := pr.Statements[0].(*RunSeqBlock)
+	if got, want := seq.Span, (Span{Start: Position{5, 1}, End: Position{10, 1}}); got != want {
+		t.Errorf("RUNSEQ span: expected %+v, got %+v", want, got)
+	}
+	task := seq.Statements[0].(*Task)
+	if got, want := task.Span, (Span{Start: Position{6, 5}, End: Position{6, 75}}); got != want {
+		t.Errorf("Task span: expected %+v, got %+v", want, got)
+	}
+	con := seq.Statements[1].(*RunConBlock)
+	if got, want := con.Span, (Span{Start: Position{7, 5}, End: Position{9, 5}}); got != want {
+		t.Errorf("RUNCON span: expected %+v, got %+v", want, got)
+	}
+	if got, want := con.Statement("CheckWeather").(*Task).Span.Start, (Position{8, 9}); got != want {
+		t.Errorf("Nested task span: expected %+v, got %+v", want, got)
+	}
+}
+
+// TestRunConOrder tests that RUNCON children keep their declaration order and stable keys.
+func TestRunConOrder(t *testing.T) {
+	input := `RUNCON {
+    TASK Zeta AGENT A PARAMETERS () ;
+    RUNSEQ { TASK Inner1 AGENT A PARAMETERS () ; }
+    TASK Alpha AGENT A PARAMETERS () ;
+    RUNCON { TASK Inner2 AGENT A PARAMETERS () ; }
+    RUNSEQ { TASK Inner3 AGENT A PARAMETERS () ; }
+}`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	con := pr.Statements[0].(*RunConBlock)
+	expected := []string{"Zeta", "RUNSEQ_0", "Alpha", "RUNCON_1", "RUNSEQ_2"}
+	if !reflect.DeepEqual(con.Keys, expected) {
+		t.Errorf("Expected keys %v, got %v", expected, con.Keys)
+	}
+	if len(con.Statements) != len(con.Keys) {
+		t.Fatalf("Expected %d statements, got %d", len(con.Keys), len(con.Statements))
+	}
+	if task, ok := con.Statements[2].(*Task); !ok || task.TaskName != "Alpha" {
+		t.Errorf("Expected third statement to be task Alpha, got %+v", con.Statements[2])
+	}
+	if _, ok := con.Statement("RUNCON_1").(*RunConBlock); !ok {
+		t.Errorf("Expected RUNCON_1 to be a RUNCON block, got %+v", con.Statement("RUNCON_1"))
+	}
+	if con.Statement("Missing") != nil {
+		t.Error("Expected nil for an unknown key")
+	}
+}
+
+// TestParameterKinds tests that parameters remember whether they are literals or references.
+func TestParameterKinds(t *testing.T) {
+	p := NewParser(NewLexer(`TASK Fetch AGENT A PARAMETERS (from=origin, label="origin", count=2) ;`))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	params := pr.Statements[0].(*Task).Parameters
+	expected := map[string]Parameter{
+		"from":  {Value: "origin", Type: IDENT},
+		"label": {Value: "origin", Type: STRING},
+		"count": {Value: "2", Type: NUMBER},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Fatalf("Expected parameters %v, got %v", expected, params)
+	}
+	if !params["from"].IsReference() || params["label"].IsReference() || params["count"].IsReference() {
+		t.Error("Expected only the bare identifier to be a reference")
+	}
+	if got := params["label"].String(); got != `"origin"` {
+		t.Errorf("Expected string literal to print quoted, got %s", got)
+	}
+}
+
+// TestParseErrors tests that errors carry positions and readable token names.
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected ParseError
+	}{
+		{
+			name:     "Missing semicolon after DATA",
+			input:    "START\nDATA x TYPE String\nEND",
+			expected: ParseError{Pos: Position{3, 1}, Message: "expected ';', got identifier 'END'"},
+		},
+		{
+			name:     "Missing AGENT keyword",
+			input:    "TASK Fetch PARAMETERS () ;",
+			expected: ParseError{Pos: Position{1, 12}, Message: "expected 'AGENT', got identifier 'PARAMETERS'"},
+		},
+		{
+			name:     "Bad parameter separator",
+			input:    "TASK Fetch AGENT A PARAMETERS (a=1 b=2) ;",
+			expected: ParseError{Pos: Position{1, 36}, Message: "expected ',' or ')' in parameters, got identifier 'b'"},
+		},
+		{
+			name:     "Duplicate task in RUNCON",
+			input:    "RUNCON {\n  TASK Fetch AGENT A PARAMETERS () ;\n  TASK Fetch AGENT B PARAMETERS () ;\n}",
+			expected: ParseError{Pos: Position{3, 3}, Message: "duplicate task name 'Fetch' in RUNCON block, first declared at 2:3"},
+		},
+		{
+			name:     "Task named like an earlier block key",
+			input:    "RUNCON {\n  RUNSEQ { }\n  TASK RUNSEQ_0 AGENT A PARAMETERS () ;\n}",
+			expected: ParseError{Pos: Position{3, 3}, Message: "task name 'RUNSEQ_0' in RUNCON block is already the key of the block at 2:3"},
+		},
+		{
+			name:     "Block key taken by an earlier task",
+			input:    "AUTO {\n  TASK RUNSEQ_0 AGENT A PARAMETERS () ;\n  RUNSEQ { }\n}",
+			expected: ParseError{Pos: Position{3, 3}, Message: "key 'RUNSEQ_0' generated for this block in AUTO block is already the name of the task at 2:3"},
+		},
+		{
+			name:     "Truncated task",
+			input:    "RUNSEQ { TASK",
+			expected: ParseError{Pos: Position{1, 14}, Message: "expected identifier, got end of input"},
+		},
+		{
+			name:     "Unclosed block",
+			input:    "RUNSEQ {\n  TASK Fetch AGENT A PARAMETERS () ;\n",
+			expected: ParseError{Pos: Position{3, 1}, Message: "expected '}' to close the RUNSEQ block opened at 1:1, got end of input"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := NewParser(NewLexer(test.input))
+			p.ParseProgram()
+
+			errors := p.Errors()
+			if len(errors) == 0 {
+				t.Fatal("Expected a parse error, but got none")
+			}
+			if errors[0] != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected.Error(), errors[0].Error())
+			}
+		})
+	}
+}
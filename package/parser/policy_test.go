@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseErrorPolicy tests ON ERROR on the script header and on RUNSEQ and RUNCON blocks.
+func TestParseErrorPolicy(t *testing.T) {
+	input := `START ON ERROR stop
+RUNSEQ ON ERROR CONTINUE {
+    RUNCON on error STOP {
+        TASK A AGENT X PARAMETERS () ;
+    }
+    RUNSEQ {
+    }
+}
+END`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	if pr.OnError != OnErrorStop {
+		t.Errorf("Expected script policy %s, got %q", OnErrorStop, pr.OnError)
+	}
+	seq := pr.Statements[0].(*RunSeqBlock)
+	if seq.OnError != OnErrorContinue {
+		t.Errorf("Expected RUNSEQ policy %s, got %q", OnErrorContinue, seq.OnError)
+	}
+	if con := seq.Statements[0].(*RunConBlock); con.OnError != OnErrorStop || len(con.Statements) != 1 {
+		t.Errorf("Expected RUNCON with policy %s and one task, got %+v", OnErrorStop, con)
+	}
+	if inner := seq.Statements[1].(*RunSeqBlock); inner.OnError != "" {
+		t.Errorf("Expected the inner RUNSEQ to inherit its policy, got %q", inner.OnError)
+	}
+}
+
+// TestErrorPolicyErrors tests malformed ON ERROR clauses.
+func TestErrorPolicyErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"START ON ERROR RETRY\nEND", "line 1, column 16: unknown ON ERROR policy 'RETRY'; use STOP or CONTINUE"},
+		{"START ON FAILURE STOP\nEND", "line 1, column 10: expected 'ERROR', got identifier 'FAILURE'"},
+		{"START ON ERROR STOP ON ERROR CONTINUE\nEND", "line 1, column 21: ON ERROR given more than once in the script header"},
+		{"RUNSEQ ON ERROR { }", "line 1, column 17: expected identifier, got '{'"},
+		{"RUNCON ON ERROR STOP TASK A AGENT X PARAMETERS () ; }", "line 1, column 22: expected '{', got identifier 'TASK'"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
+
+// TestParseDeadline tests DEADLINE on the script header and on RUNSEQ and RUNCON blocks, alone and with ON ERROR.
+func TestParseDeadline(t *testing.T) {
+	input := `START DEADLINE 5m ON ERROR STOP
+RUNSEQ deadline 30s {
+    RUNCON ON ERROR CONTINUE DEADLINE 500ms {
+        TASK A AGENT X PARAMETERS () ;
+    }
+}
+END`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	if pr.Deadline != 5*time.Minute || pr.OnError != OnErrorStop {
+		t.Errorf("Expected script deadline 5m and policy %s, got %s and %q", OnErrorStop, pr.Deadline, pr.OnError)
+	}
+	seq := pr.Statements[0].(*RunSeqBlock)
+	if seq.Deadline != 30*time.Second || seq.OnError != "" {
+		t.Errorf("Expected RUNSEQ deadline 30s and no policy, got %s and %q", seq.Deadline, seq.OnError)
+	}
+	if con := seq.Statements[0].(*RunConBlock); con.Deadline != 500*time.Millisecond || con.OnError != OnErrorContinue {
+		t.Errorf("Expected RUNCON deadline 500ms and policy %s, got %s and %q", OnErrorContinue, con.Deadline, con.OnError)
+	}
+}
+
+// TestDeadlineErrors tests malformed DEADLINE clauses.
+func TestDeadlineErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"START DEADLINE\nEND", "line 2, column 1: expected duration, got identifier 'END'"},
+		{"START DEADLINE 1m DEADLINE 2m\nEND", "line 1, column 19: DEADLINE given more than once in the script header"},
+		{"RUNSEQ DEADLINE 1s ON ERROR STOP DEADLINE 2s { }", "line 1, column 34: DEADLINE given more than once for the RUNSEQ block at 1:1"},
+		{"RUNCON DEADLINE 10 { }", "line 1, column 17: expected duration, got number '10'"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
+
+// TestParseRunConMax tests the MAX clause of RUNCON blocks alongside the other options.
+func TestParseRunConMax(t *testing.T) {
+	input := `RUNCON DEADLINE 1m max 4 ON ERROR STOP {
+    TASK A AGENT X PARAMETERS () ;
+    RUNCON {
+        TASK B AGENT X PARAMETERS () ;
+    }
+}`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	con := pr.Statements[0].(*RunConBlock)
+	if con.Max != 4 || con.Deadline != time.Minute || con.OnError != OnErrorStop {
+		t.Errorf("Expected MAX 4, deadline 1m and policy %s, got %d, %s and %q", OnErrorStop, con.Max, con.Deadline, con.OnError)
+	}
+	if nested := con.Statements[1].(*RunConBlock); nested.Max != 0 {
+		t.Errorf("Expected no limit on the nested RUNCON, got MAX %d", nested.Max)
+	}
+}
+
+// TestRunConMaxErrors tests malformed MAX clauses and MAX on blocks that do not take it.
+func TestRunConMaxErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"RUNCON MAX 0 { }", "line 1, column 12: MAX must be a positive whole number, got number '0'"},
+		{"RUNCON MAX { }", "line 1, column 12: expected number, got '{'"},
+		{"RUNCON MAX 2 MAX 3 { }", "line 1, column 14: MAX given more than once for the RUNCON block at 1:1"},
+		{"RUNSEQ MAX 2 { }", "line 1, column 8: expected '{', got identifier 'MAX'"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseRunAnyBlock tests RUNANY and RUNQUORUM blocks, alone and nested in RUNCON.
+func TestParseRunAnyBlock(t *testing.T) {
+	input := `START
+RUNANY DEADLINE 10s {
+    TASK CheckWeatherA AGENT WeatherA PARAMETERS (OUTPUT=weather) ;
+    TASK CheckWeatherB AGENT WeatherB PARAMETERS (OUTPUT=weather) ;
+}
+RUNCON {
+    runquorum 2 {
+        TASK A AGENT X PARAMETERS () ;
+        TASK B AGENT Y PARAMETERS () ;
+        RUNSEQ {
+            TASK C AGENT Z PARAMETERS () ;
+        }
+    }
+}
+END`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	anyBlock := pr.Statements[0].(*RunAnyBlock)
+	if !anyBlock.Any || anyBlock.Quorum != 1 || anyBlock.Deadline != 10*time.Second || len(anyBlock.Statements) != 2 {
+		t.Errorf("Expected RUNANY with a 10s deadline and two tasks, got %+v", anyBlock)
+	}
+
+	con := pr.Statements[1].(*RunConBlock)
+	quorum, ok := con.Statement("RUNQUORUM_0").(*RunAnyBlock)
+	if !ok {
+		t.Fatalf("Expected RUNCON child RUNQUORUM_0, got keys %v", con.Keys)
+	}
+	if quorum.Any || quorum.Quorum != 2 || len(quorum.Statements) != 3 {
+		t.Errorf("Expected RUNQUORUM 2 with three statements, got %+v", quorum)
+	}
+	if quorum.Keyword() != "RUNQUORUM" || anyBlock.Keyword() != "RUNANY" {
+		t.Errorf("Expected keywords RUNANY and RUNQUORUM, got %s and %s", anyBlock.Keyword(), quorum.Keyword())
+	}
+}
+
+// TestRunAnyBlockErrors tests malformed RUNANY and RUNQUORUM blocks.
+func TestRunAnyBlockErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"RUNQUORUM { }", "line 1, column 11: expected number, got '{'"},
+		{"RUNQUORUM 0 { }", "line 1, column 11: RUNQUORUM must be a positive whole number, got number '0'"},
+		{"RUNQUORUM 3 {\n    TASK A AGENT X PARAMETERS () ;\n    TASK B AGENT Y PARAMETERS () ;\n}", "line 1, column 1: RUNQUORUM 3 needs at least 3 statements in its block, got 2"},
+		{"RUNANY { }", "line 1, column 1: RUNANY needs at least one statement in its block"},
+		{"RUNANY ON ERROR STOP { }", "line 1, column 8: expected '{', got identifier 'ON'"},
+		{"RUNANY DEADLINE 1s DEADLINE 2s { }", "line 1, column 20: DEADLINE given more than once for the RUNANY block at 1:1"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseTaskOptions tests the RETRY, BACKOFF and TIMEOUT clauses of a task.
+func TestParseTaskOptions(t *testing.T) {
+	tests := []struct {
+		input   string
+		retry   int
+		backoff Backoff
+		timeout time.Duration
+	}{
+		{"TASK A AGENT X PARAMETERS () ;", 0, Backoff{}, 0},
+		{"TASK A AGENT X PARAMETERS () RETRY 3 ;", 3, Backoff{}, 0},
+		{"TASK A AGENT X PARAMETERS () RETRY 3 BACKOFF exponential TIMEOUT 10s ;", 3, Backoff{Strategy: BackoffExponential}, 10 * time.Second},
+		{"TASK A AGENT X PARAMETERS () timeout 500ms backoff Linear 2s retry 1 ;", 1, Backoff{Strategy: BackoffLinear, Delay: 2 * time.Second}, 500 * time.Millisecond},
+		{"TASK A AGENT X PARAMETERS () RETRY 2 BACKOFF fixed 250ms ;", 2, Backoff{Strategy: BackoffFixed, Delay: 250 * time.Millisecond}, 0},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer("START\n" + test.input + "\nEND"))
+		pr := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Errorf("Input %q: unexpected errors %v", test.input, p.Errors())
+			continue
+		}
+		task := pr.Statements[0].(*Task)
+		if task.Retry != test.retry || task.Backoff != test.backoff || task.Timeout != test.timeout {
+			t.Errorf("Input %q: expected retry %d, backoff %+v, timeout %s; got %d, %+v, %s",
+				test.input, test.retry, test.backoff, test.timeout, task.Retry, task.Backoff, task.Timeout)
+		}
+	}
+}
+
+// TestTaskOptionErrors tests that malformed task clauses are reported.
+func TestTaskOptionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"TASK A AGENT X PARAMETERS () RETRY 0 ;", "line 1, column 36: RETRY must be a positive whole number, got number '0'"},
+		{"TASK A AGENT X PARAMETERS () RETRY three ;", "line 1, column 36: expected number, got identifier 'three'"},
+		{"TASK A AGENT X PARAMETERS () RETRY 2.5 ;", "line 1, column 36: RETRY must be a positive whole number, got number '2.5'"},
+		{"TASK A AGENT X PARAMETERS () RETRY 2 BACKOFF random ;", "line 1, column 46: unknown BACKOFF strategy 'random'; use fixed, linear or exponential"},
+		{"TASK A AGENT X PARAMETERS () TIMEOUT 10 ;", "line 1, column 38: expected duration, got number '10'"},
+		{"TASK A AGENT X PARAMETERS () RETRY 2 RETRY 3 ;", "line 1, column 38: RETRY given more than once for TASK A"},
+		{"TASK A AGENT X PARAMETERS () BACKOFF fixed ;", "line 1, column 1: BACKOFF on TASK A has no effect without RETRY"},
+		{"TASK A AGENT X PARAMETERS () RETRY 2 EVERY 1s ;", "line 1, column 38: expected ';' after TASK A, got identifier 'EVERY'"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseTryBlock tests TRY blocks with CATCH, FINALLY or both.
+func TestParseTryBlock(t *testing.T) {
+	input := `START
+TRY {
+    RUNSEQ {
+        TASK ScheduleFlight AGENT FlightGetter PARAMETERS (OUTPUT=flightInfo) ;
+    }
+} CATCH {
+    TASK NotifyUser AGENT Notifier PARAMETERS (task=errorTask, message=errorMessage) ;
+} FINALLY {
+    TASK Cleanup AGENT Janitor PARAMETERS () ;
+}
+RUNCON {
+    try {
+    } finally {
+    }
+}
+END`
+
+	p := NewParser(NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	tryBlock := pr.Statements[0].(*TryBlock)
+	if got, want := tryBlock.Span, (Span{Start: Position{2, 1}, End: Position{10, 1}}); got != want {
+		t.Errorf("TRY span: expected %+v, got %+v", want, got)
+	}
+	clearSpans(pr)
+
+	expected := &TryBlock{
+		Body: []Statement{
+			&RunSeqBlock{Statements: []Statement{
+				&Task{
+					TaskName:   "ScheduleFlight",
+					AgentName:  "FlightGetter",
+					Parameters: map[string]Parameter{"OUTPUT": {Value: "flightInfo", Type: IDENT}},
+				},
+			}},
+		},
+		Catch: []Statement{
+			&Task{
+				TaskName:  "NotifyUser",
+				AgentName: "Notifier",
+				Parameters: map[string]Parameter{
+					"task":    {Value: CatchTaskVar, Type: IDENT},
+					"message": {Value: CatchMessageVar, Type: IDENT},
+				},
+			},
+		},
+		Finally: []Statement{
+			&Task{TaskName: "Cleanup", AgentName: "Janitor", Parameters: map[string]Parameter{}},
+		},
+	}
+	if !reflect.DeepEqual(tryBlock, expected) {
+		t.Errorf("Unexpected TRY block:\nExpected: %+v\nGot: %+v", expected, tryBlock)
+	}
+
+	con := pr.Statements[1].(*RunConBlock)
+	if !reflect.DeepEqual(con.Keys, []string{"TRY_0"}) {
+		t.Errorf("Expected RUNCON keys [TRY_0], got %v", con.Keys)
+	}
+	nested := con.Statements[0].(*TryBlock)
+	if nested.Catch != nil || nested.Finally == nil {
+		t.Errorf("Expected a TRY with only FINALLY, got %+v", nested)
+	}
+}
+
+// TestTryBlockErrors tests that TRY needs a handler and that every clause is closed.
+func TestTryBlockErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"TRY { } END", "line 1, column 9: expected CATCH or FINALLY after the TRY block opened at 1:1, got identifier 'END'"},
+		{"TRY { } CATCH TASK", "line 1, column 15: expected '{', got identifier 'TASK'"},
+		{"TRY { } CATCH { } FINALLY {", "line 1, column 28: expected '}' to close the FINALLY block opened at 1:19, got end of input"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("Input %q: expected an error, got none", test.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != test.expected {
+			t.Errorf("Input %q: expected %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
+
+// TestWalkTryBlock tests that the body, CATCH and FINALLY of a TRY block are walked in order.
+func TestWalkTryBlock(t *testing.T) {
+	p := NewParser(NewLexer(`START
+TRY { TASK Body AGENT A PARAMETERS () ; } CATCH { TASK Handler AGENT B PARAMETERS () ; } FINALLY { TASK Cleanup AGENT C PARAMETERS () ; }
+END`))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	var visited []string
+	InspectAll(pr.Statements, func(stmt Statement) bool {
+		if task, ok := stmt.(*Task); ok {
+			visited = append(visited, task.TaskName)
+		}
+		return true
+	})
+	if expected := []string{"Body", "Handler", "Cleanup"}; !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Expected visit order %v, got %v", expected, visited)
+	}
+}
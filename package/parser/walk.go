@@ -0,0 +1,80 @@
+package parser
+
+import "fmt"
+
+// Visitor is called by Walk for each statement it reaches. If Visit returns a non-nil
+// Visitor w, Walk visits the statement's children with w and then calls w.Visit(nil).
+type Visitor interface {
+	Visit(stmt Statement) (w Visitor)
+}
+
+// Walk traverses a statement and its children in depth-first, declaration order. The compensation of a
+// task is its only child.
+func Walk(v Visitor, stmt Statement) {
+	if v = v.Visit(stmt); v == nil {
+		return
+	}
+
+	switch s := stmt.(type) {
+	case *Task:
+		if s.Compensation != nil {
+			Walk(v, s.Compensation)
+		}
+	case *RunSeqBlock:
+		walkList(v, s.Statements)
+	case *RunConBlock:
+		walkList(v, s.Statements)
+	case *RunAnyBlock:
+		walkList(v, s.Statements)
+	case *AutoBlock:
+		walkList(v, s.Statements)
+	case *IfBlock:
+		walkList(v, s.Then)
+		walkList(v, s.Else)
+	case *ForEachBlock:
+		walkList(v, s.Body)
+	case *LoopBlock:
+		walkList(v, s.Body)
+	case *TryBlock:
+		walkList(v, s.Body)
+		walkList(v, s.Catch)
+		walkList(v, s.Finally)
+	default:
+		panic(fmt.Sprintf("parser.Walk: unexpected statement %T", stmt))
+	}
+
+	v.Visit(nil)
+}
+
+// WalkAll walks each statement in order, such as the top-level statements of a ParentRequest.
+func WalkAll(v Visitor, statements []Statement) {
+	walkList(v, statements)
+}
+
+func walkList(v Visitor, statements []Statement) {
+	for _, stmt := range statements {
+		Walk(v, stmt)
+	}
+}
+
+// inspector adapts a function to the Visitor interface.
+type inspector func(Statement) bool
+
+func (f inspector) Visit(stmt Statement) Visitor {
+	if f(stmt) {
+		return f
+	}
+	return nil
+}
+
+// Inspect walks a statement in depth-first order, calling f for it and each of its children.
+// If f returns false the children of that statement are skipped. After the children of a
+// statement have been visited, f is called with nil.
+func Inspect(stmt Statement, f func(Statement) bool) {
+	Walk(inspector(f), stmt)
+}
+
+// InspectAll calls Inspect on each statement in order.
+func InspectAll(statements []Statement, f func(Statement) bool) {
+	WalkAll(inspector(f), statements)
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+const walkScript = `START
+RUNSEQ {
+    TASK First AGENT A PARAMETERS () ;
+    RUNCON {
+        TASK Left AGENT B PARAMETERS () ;
+        RUNSEQ {
+            TASK Inner AGENT C PARAMETERS () ;
+        }
+    }
+}
+TASK Last AGENT D PARAMETERS () COMPENSATE WITH TASK UndoLast AGENT D PARAMETERS () ;
+END`
+
+// describeStatement names a statement for comparing traversal orders.
+func describeStatement(stmt Statement) string {
+	switch s := stmt.(type) {
+	case nil:
+		return "end"
+	case *Task:
+		return s.TaskName
+	case *RunSeqBlock:
+		return "RUNSEQ"
+	case *RunConBlock:
+		return "RUNCON"
+	default:
+		return "unknown"
+	}
+}
+
+// TestInspectAll tests that statements are visited depth-first in declaration order.
+func TestInspectAll(t *testing.T) {
+	p := NewParser(NewLexer(walkScript))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	var visited []string
+	InspectAll(pr.Statements, func(stmt Statement) bool {
+		visited = append(visited, describeStatement(stmt))
+		return true
+	})
+
+	expected := []string{
+		"RUNSEQ", "First", "end", "RUNCON", "Left", "end", "RUNSEQ", "Inner", "end", "end", "end", "end",
+		"Last", "UndoLast", "end", "end",
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Expected visit order %v, got %v", expected, visited)
+	}
+}
+
+// TestInspectSkipsChildren tests that returning false prunes a statement's children.
+func TestInspectSkipsChildren(t *testing.T) {
+	p := NewParser(NewLexer(walkScript))
+	pr := p.ParseProgram()
+
+	var tasks []string
+	InspectAll(pr.Statements, func(stmt Statement) bool {
+		switch s := stmt.(type) {
+		case *RunConBlock:
+			return false
+		case *Task:
+			tasks = append(tasks, s.TaskName)
+		}
+		return true
+	})
+
+	expected := []string{"First", "Last", "UndoLast"}
+	if !reflect.DeepEqual(tasks, expected) {
+		t.Errorf("Expected tasks %v, got %v", expected, tasks)
+	}
+}
+
+// taskCounter is a Visitor that counts tasks by agent.
+type taskCounter map[string]int
+
+func (c taskCounter) Visit(stmt Statement) Visitor {
+	if task, ok := stmt.(*Task); ok {
+		c[task.AgentName]++
+	}
+	return c
+}
+
+// TestWalk tests walking a single block with a custom Visitor.
+func TestWalk(t *testing.T) {
+	p := NewParser(NewLexer(walkScript))
+	pr := p.ParseProgram()
+
+	counts := taskCounter{}
+	Walk(counts, pr.Statements[0])
+
+	expected := taskCounter{"A": 1, "B": 1, "C": 1}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expected counts %v, got %v", expected, counts)
+	}
+}
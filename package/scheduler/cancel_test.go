@@ -0,0 +1,69 @@
+package scheduler_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"trace/package/logger"
+	"trace/package/scheduler"
+	"trace/package/task"
+)
+
+// TestRunParentRequest_Cancelled tests that cancelling a run reports every task that has not finished as
+// Cancelled, including the tasks of nested blocks that never started, under both ON ERROR policies.
+func TestRunParentRequest_Cancelled(t *testing.T) {
+	for _, policy := range []string{"CONTINUE", "STOP"} {
+		t.Run(policy, func(t *testing.T) {
+			pr := parseScript(t, `START ON ERROR `+policy+`
+RUNSEQ {
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="slow") ;
+    RUNCON {
+        TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") ;
+        RUNSEQ {
+            TASK BookDinner AGENT Restaurants PARAMETERS (task="dinner", message="") ;
+        }
+    }
+    TASK SendItinerary AGENT Mailer PARAMETERS (task="itinerary", message="") ;
+}
+END`)
+			e, _ := newRecordingExecutor(t, "Hotels", "Rides", "Restaurants", "Mailer")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			time.AfterFunc(30*time.Millisecond, cancel)
+			l := logger.NewLogger()
+			result := scheduler.RunParentRequest(ctx, pr, e, l)
+			if result.Succeeded() {
+				t.Fatal("Expected the run to fail")
+			}
+
+			statuses := make(map[string]task.Status)
+			for _, r := range result.Tasks {
+				statuses[r.Path] = r.Status
+			}
+			expected := map[string]task.Status{
+				"RUNSEQ[0]/BookHotel":                     task.Cancelled,
+				"RUNSEQ[0]/RUNCON[1]/ScheduleRide":        task.Cancelled,
+				"RUNSEQ[0]/RUNCON[1]/RUNSEQ_0/BookDinner": task.Cancelled,
+				"RUNSEQ[0]/SendItinerary":                 task.Cancelled,
+			}
+			if !reflect.DeepEqual(statuses, expected) {
+				t.Errorf("Expected task statuses %v, got %v", expected, statuses)
+			}
+
+			all := l.Text()
+			for _, expected := range []string{
+				"Task BookHotel cancelled while calling agent Hotels: context canceled",
+				"Task ScheduleRide cancelled before it started: context canceled",
+				"Task BookDinner cancelled before it started: context canceled",
+				"Task SendItinerary cancelled before it started: context canceled",
+			} {
+				if !strings.Contains(all, expected) {
+					t.Errorf("Expected log %q, got:\n%s", expected, all)
+				}
+			}
+		})
+	}
+}
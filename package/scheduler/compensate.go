@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"trace/package/logger"
+	"trace/package/parser"
+)
+
+// compensation is the undo step of a completed task, kept with the data and permissions the task ran with.
+type compensation struct {
+	task        *parser.Task
+	compensates string
+	path        string // Block path of the compensated task
+	globalData  map[string]*parser.Data
+	permissions map[string]*parser.Permission
+}
+
+// compensationStack records the compensations of completed tasks in the order the tasks finished.
+// Concurrent branches push to the same stack.
+type compensationStack struct {
+	steps []compensation
+	mu    sync.Mutex
+}
+
+// push records the compensation of a task that just completed.
+func (s *compensationStack) push(steps ...compensation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, steps...)
+}
+
+// drain empties the stack and returns what it held, oldest first.
+func (s *compensationStack) drain() []compensation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	steps := s.steps
+	s.steps = nil
+	return steps
+}
+
+// Compensate runs the compensations of every task completed so far, most recent first, and forgets them.
+// A failed compensation is logged and does not stop the others. Compensations run even if ctx is cancelled.
+func (r *Runner) Compensate(ctx context.Context) error {
+	ctx = context.WithoutCancel(ctx)
+	steps := r.compensations.drain()
+	if len(steps) == 0 {
+		return nil
+	}
+	r.Logger.AddLog(logger.NewLog(fmt.Sprintf("Compensating %d completed tasks in reverse order", len(steps))))
+
+	var errs []error
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		n := len(steps) - i
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("Compensation %d of %d: running %s to undo %s", n, len(steps), step.task.TaskName, step.compensates)))
+		result, err := runTask(ctx, step.task, step.globalData, step.permissions, r.Executor, r.Logger)
+		result.Path = step.path + "/COMPENSATE/" + step.task.TaskName
+		r.results.add(result)
+		if err != nil {
+			r.Logger.AddLog(logger.NewLog(fmt.Sprintf("Compensation %d of %d: %s failed: %v", n, len(steps), step.task.TaskName, err)))
+			errs = append(errs, fmt.Errorf("compensating %s: %w", step.compensates, err))
+			continue
+		}
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("Compensation %d of %d: %s undid %s", n, len(steps), step.task.TaskName, step.compensates)))
+	}
+	return errors.Join(errs...)
+}
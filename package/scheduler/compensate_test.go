@@ -0,0 +1,111 @@
+package scheduler_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"trace/package/logger"
+	"trace/package/scheduler"
+)
+
+const sagaScript = `START
+RUNSEQ {
+    TASK ScheduleFlight AGENT Flights PARAMETERS (task="flight", message="")
+        COMPENSATE WITH TASK CancelFlight AGENT FlightDesk PARAMETERS (task="flight", message="cancel") ;
+    RUNCON {
+        TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="")
+            COMPENSATE WITH TASK CancelHotel AGENT HotelDesk PARAMETERS (task="hotel", message="cancel") ;
+        TASK RentCar AGENT Cars PARAMETERS (task="car", message="slow")
+            COMPENSATE WITH TASK ReturnCar AGENT CarDesk PARAMETERS (task="car", message="cancel") ;
+    }
+    TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") ;
+}
+END`
+
+// TestCompensate tests that a failed run undoes its completed tasks, including concurrent ones, most recent first.
+func TestCompensate(t *testing.T) {
+	pr := parseScript(t, sagaScript)
+	e, recorder := newRecordingExecutor(t, "Flights", "Hotels", "Cars", "FlightDesk", "HotelDesk", "CarDesk")
+
+	l := logger.NewLogger()
+	if scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+
+	if len(recorder.calls) != 6 {
+		t.Fatalf("Expected 3 tasks and 3 compensations, got calls %v", recorder.calls)
+	}
+	if recorder.calls[0] != "Flights" || recorder.calls[5] != "FlightDesk" {
+		t.Errorf("Expected the flight to be booked first and cancelled last, got calls %v", recorder.calls)
+	}
+
+	// The concurrent bookings are undone in the reverse of the order they finished; the slow car finishes last
+	booked := recorder.calls[1:3]
+	undone := append([]string{}, recorder.calls[3:5]...)
+	undoes := map[string]string{"HotelDesk": "Hotels", "CarDesk": "Cars"}
+	if undoes[undone[0]] != booked[1] || undoes[undone[1]] != booked[0] {
+		t.Errorf("Expected %v to be undone in reverse order, got %v", booked, undone)
+	}
+	sort.Strings(undone)
+	if !reflect.DeepEqual(undone, []string{"CarDesk", "HotelDesk"}) {
+		t.Errorf("Expected the hotel and car to be undone, got %v", undone)
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		"Compensating 3 completed tasks in reverse order",
+		"Compensation 3 of 3: running CancelFlight to undo ScheduleFlight",
+		"Compensation 3 of 3: CancelFlight undid ScheduleFlight",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
+
+// TestCompensate_Success tests that nothing is undone when the run succeeds.
+func TestCompensate_Success(t *testing.T) {
+	pr := parseScript(t, sagaScript)
+	e, recorder := newRecordingExecutor(t, "Flights", "Hotels", "Cars", "Rides", "FlightDesk", "HotelDesk", "CarDesk")
+
+	if !scheduler.RunParentRequest(context.Background(), pr, e, logger.NewLogger()).Succeeded() {
+		t.Fatal("RunParentRequest failed")
+	}
+	for _, call := range recorder.calls {
+		if strings.HasSuffix(call, "Desk") {
+			t.Errorf("Expected no compensations, got calls %v", recorder.calls)
+			break
+		}
+	}
+}
+
+// TestCompensate_Try tests that a failed TRY body is compensated before CATCH runs, and that a successful
+// body's compensations are kept for a later failure.
+func TestCompensate_Try(t *testing.T) {
+	pr := parseScript(t, `START
+TRY {
+    TASK ScheduleFlight AGENT Flights PARAMETERS (task="flight", message="")
+        COMPENSATE WITH TASK CancelFlight AGENT FlightDesk PARAMETERS (task="flight", message="cancel") ;
+    TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") ;
+} CATCH {
+    TASK NotifyUser AGENT Notifier PARAMETERS (task=errorTask, message=errorMessage) ;
+}
+TRY {
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="")
+        COMPENSATE WITH TASK CancelHotel AGENT HotelDesk PARAMETERS (task="hotel", message="cancel") ;
+} FINALLY {
+}
+TASK Pay AGENT Bank PARAMETERS (task="pay", message="") ;
+END`)
+	e, recorder := newRecordingExecutor(t, "Flights", "FlightDesk", "Notifier", "Hotels", "HotelDesk")
+
+	if scheduler.RunParentRequest(context.Background(), pr, e, logger.NewLogger()).Succeeded() {
+		t.Fatal("Expected the failed payment to fail the run")
+	}
+	expected := []string{"Flights", "FlightDesk", "Notifier", "Hotels", "HotelDesk"}
+	if !reflect.DeepEqual(recorder.calls, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.calls)
+	}
+}
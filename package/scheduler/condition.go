@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"trace/package/parser"
+)
+
+// EvaluateCondition evaluates an IF condition against the current global data. Along with the result it
+// returns the condition with each reference replaced by its value, which explains why it held or not.
+func EvaluateCondition(c *parser.Condition, globalData map[string]*parser.Data) (bool, string, error) {
+	left, err := resolveOperand(c.Left, globalData)
+	if err != nil {
+		return false, "", err
+	}
+	if c.Operator == parser.OpIsEmpty || c.Operator == parser.OpIsNotEmpty {
+		reason := fmt.Sprintf("%q %s", left, c.Operator)
+		empty := strings.TrimSpace(left) == ""
+		return empty == (c.Operator == parser.OpIsEmpty), reason, nil
+	}
+
+	right, err := resolveOperand(c.Right, globalData)
+	if err != nil {
+		return false, "", err
+	}
+	reason := fmt.Sprintf("%q %s %q", left, c.Operator, right)
+
+	switch c.Operator {
+	case parser.OpContains:
+		return strings.Contains(left, right), reason, nil
+	case parser.OpEqual, parser.OpNotEqual:
+		// Numbers are compared by value, so 2 equals 2.0
+		equal := left == right
+		if l, r, ok := parseNumbers(left, right); ok {
+			equal = l == r
+		}
+		return equal == (c.Operator == parser.OpEqual), reason, nil
+	}
+
+	l, r, ok := parseNumbers(left, right)
+	if !ok {
+		return false, "", fmt.Errorf("cannot compare %s: both values must be numbers", reason)
+	}
+	switch c.Operator {
+	case parser.OpLess:
+		return l < r, reason, nil
+	case parser.OpLessEqual:
+		return l <= r, reason, nil
+	case parser.OpGreater:
+		return l > r, reason, nil
+	case parser.OpGreaterEqual:
+		return l >= r, reason, nil
+	default:
+		return false, "", fmt.Errorf("unknown condition operator '%s'", c.Operator)
+	}
+}
+
+// resolveOperand returns a literal as written, or the current value of the global data it references.
+func resolveOperand(operand parser.Parameter, globalData map[string]*parser.Data) (string, error) {
+	if !operand.IsReference() {
+		return operand.Value, nil
+	}
+	data, found := globalData[operand.Value]
+	if !found {
+		return "", fmt.Errorf("condition refers to undeclared data '%s'", operand.Value)
+	}
+	data.Mu.Lock()
+	defer data.Mu.Unlock()
+	return data.InitialValue, nil
+}
+
+// decimalNumber matches plain decimal numbers such as 2, -3.5 or .5. Other forms strconv accepts, such as
+// NaN, Inf and hex floats, are not numbers in conditions and are compared as text.
+var decimalNumber = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+
+// parseNumbers parses both values as numbers, reporting false if either is not a plain decimal number.
+func parseNumbers(left string, right string) (float64, float64, bool) {
+	left, right = strings.TrimSpace(left), strings.TrimSpace(right)
+	if !decimalNumber.MatchString(left) || !decimalNumber.MatchString(right) {
+		return 0, 0, false
+	}
+	l, err := strconv.ParseFloat(left, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	r, err := strconv.ParseFloat(right, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return l, r, true
+}
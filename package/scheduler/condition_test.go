@@ -0,0 +1,158 @@
+package scheduler_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"trace/package/agent"
+	"trace/package/executor"
+	"trace/package/logger"
+	"trace/package/parser"
+	"trace/package/scheduler"
+)
+
+// agentHandler answers a call to the named in-process agent.
+type agentHandler func(agentName string, jsonPayload string) (string, error)
+
+// newInProcessExecutor returns an executor whose agents are in-process functions that pass every call to
+// handle. Each agent fills in the given JSON template.
+func newInProcessExecutor(t *testing.T, jsonBody map[string]interface{}, handle agentHandler, agentNames ...string) *executor.Executor {
+	t.Helper()
+	registry := agent.NewMemoryRegistry()
+	e := executor.NewExecutor(registry, time.Second)
+	for i, name := range agentNames {
+		a := agent.NewBaseAgent("AG"+string(rune('A'+i)), name, "Test", name, jsonBody, nil)
+		a.Transport = agent.TransportInProcess
+		if err := registry.Register(a); err != nil {
+			t.Fatalf("Register(%s) failed: %v", name, err)
+		}
+		agentName := name
+		e.RegisterAgentFunc(name, func(jsonPayload string) (string, error) {
+			return handle(agentName, jsonPayload)
+		})
+	}
+	return e
+}
+
+// parseScript parses a script and fails the test on syntax errors.
+func parseScript(t *testing.T, input string) *parser.ParentRequest {
+	t.Helper()
+	p := parser.NewParser(parser.NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+	return pr
+}
+
+// TestEvaluateCondition tests each operator against global data and literals.
+func TestEvaluateCondition(t *testing.T) {
+	globalData := map[string]*parser.Data{
+		"weather":  {DataName: "weather", InitialValue: "sunny, 24C"},
+		"guests":   {DataName: "guests", InitialValue: "2"},
+		"notes":    {DataName: "notes", InitialValue: "  "},
+		"location": {DataName: "location", InitialValue: "Chicago"},
+		"reading":  {DataName: "reading", InitialValue: "NaN"},
+		"limit":    {DataName: "limit", InitialValue: "inf"},
+		"code":     {DataName: "code", InitialValue: "0x1p0"},
+	}
+
+	tests := []struct {
+		condition string
+		expected  bool
+	}{
+		{`weather CONTAINS "sunny"`, true},
+		{`weather CONTAINS "rain"`, false},
+		{`guests == 2.0`, true},
+		{`guests != "2"`, false},
+		{`location == "Chicago"`, true},
+		{`location == "chicago"`, false},
+		{`guests < 3`, true},
+		{`guests <= 2`, true},
+		{`guests > 2`, false},
+		{`guests >= 10`, false},
+		{`notes IS EMPTY`, true},
+		{`location IS NOT EMPTY`, true},
+		{`reading == "NaN"`, true},
+		{`reading != "NaN"`, false},
+		{`limit == "infinity"`, false},
+		{`code == 1`, false},
+		{`guests == "+2."`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			pr := parseScript(t, "IF "+tt.condition+" { }")
+			result, reason, err := scheduler.EvaluateCondition(pr.Statements[0].(*parser.IfBlock).Condition, globalData)
+			if err != nil {
+				t.Fatalf("EvaluateCondition failed: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v (%s)", tt.expected, result, reason)
+			}
+		})
+	}
+}
+
+// TestEvaluateCondition_Errors tests conditions that cannot be evaluated.
+func TestEvaluateCondition_Errors(t *testing.T) {
+	globalData := map[string]*parser.Data{
+		"location": {DataName: "location", InitialValue: "Chicago"},
+	}
+
+	tests := []struct {
+		condition string
+		expected  string
+	}{
+		{`missing IS EMPTY`, "undeclared data 'missing'"},
+		{`location > 3`, "both values must be numbers"},
+		{`"Inf" > 3`, "both values must be numbers"},
+	}
+
+	for _, tt := range tests {
+		pr := parseScript(t, "IF "+tt.condition+" { }")
+		_, _, err := scheduler.EvaluateCondition(pr.Statements[0].(*parser.IfBlock).Condition, globalData)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Condition %s: expected error containing %q, got %v", tt.condition, tt.expected, err)
+		}
+	}
+}
+
+// TestRunIfBlock tests that only the selected branch runs and the decision is logged.
+func TestRunIfBlock(t *testing.T) {
+	pr := parseScript(t, `START
+DATA weatherInfo TYPE String VALUE "rain expected" ;
+IF weatherInfo CONTAINS "sunny" {
+    TASK BookHotel AGENT RoomBooker PARAMETERS () ;
+} ELSE IF weatherInfo CONTAINS "rain" {
+    TASK BookMuseum AGENT Planner PARAMETERS () ;
+} ELSE {
+    TASK StayHome AGENT Planner PARAMETERS () ;
+}
+END`)
+
+	var calls []string
+	e := newInProcessExecutor(t, map[string]interface{}{}, func(agentName string, jsonPayload string) (string, error) {
+		calls = append(calls, agentName)
+		return agentName + " done", nil
+	}, "RoomBooker", "Planner")
+	l := logger.NewLogger()
+	if !scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatal("RunParentRequest failed")
+	}
+
+	if len(calls) != 1 || calls[0] != "Planner" {
+		t.Errorf("Expected only the museum booking to run, got calls %v", calls)
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		`IF weatherInfo CONTAINS "sunny" at 3:1 is false ("rain expected" CONTAINS "sunny"); running ELSE branch`,
+		`IF weatherInfo CONTAINS "rain" at 5:8 is true ("rain expected" CONTAINS "rain"); running THEN branch`,
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
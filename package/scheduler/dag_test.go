@@ -0,0 +1,106 @@
+package scheduler_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"trace/package/logger"
+	"trace/package/scheduler"
+)
+
+// TestRunConBlock_After tests that each task starts as soon as the statements it runs AFTER have finished,
+// sees their outputs, and is skipped if one of them failed.
+func TestRunConBlock_After(t *testing.T) {
+	pr := parseScript(t, `START
+DATA flightInfo TYPE String ;
+PERM AGENT Flights DATA flightInfo ACCESS WRITE ;
+PERM AGENT Mailer DATA flightInfo ACCESS READ ;
+RUNCON {
+    TASK SendItinerary AGENT Mailer PARAMETERS (task="itinerary", message=flightInfo) AFTER ScheduleFlight ;
+    TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") AFTER BookHotel ;
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="slow") ;
+    TASK ScheduleFlight AGENT Flights PARAMETERS (task="flight", message="", OUTPUT=flightInfo) ;
+    TASK RentCar AGENT Cars PARAMETERS (task="car", message="fail") ;
+    TASK InsureCar AGENT Insurer PARAMETERS (task="insurance", message="") AFTER RentCar ;
+}
+END`)
+	e, recorder := newRecordingExecutor(t, "Mailer", "Rides", "Hotels", "Flights", "Cars", "Insurer")
+
+	l := logger.NewLogger()
+	if scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+	if expected := []string{"Flights", "Mailer", "Cars", "Hotels", "Rides"}; !reflect.DeepEqual(recorder.called(), expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.called())
+	}
+	if got := recorder.payloads["Mailer"]["message"]; got != "Flights done" {
+		t.Errorf("Expected the itinerary to see the flight, got %q", got)
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		"Starting TASK SendItinerary at 6:5: ScheduleFlight finished",
+		"Starting TASK ScheduleRide at 7:5: BookHotel finished",
+		"Skipping TASK InsureCar at 11:5: it runs AFTER RentCar, which failed",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
+
+// TestRunConBlock_Cycle tests that a block whose AFTER clauses form a cycle fails without running anything.
+func TestRunConBlock_Cycle(t *testing.T) {
+	pr := parseScript(t, `START
+RUNCON {
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="") AFTER ScheduleRide ;
+    TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") AFTER BookHotel ;
+}
+END`)
+	e, recorder := newRecordingExecutor(t, "Hotels", "Rides")
+
+	l := logger.NewLogger()
+	if scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+	if calls := recorder.called(); len(calls) != 0 {
+		t.Errorf("Expected no calls, got %v", calls)
+	}
+	if all := l.Text(); !strings.Contains(all, "Error: RUNCON at 2:1: dependency cycle: BookHotel -> ScheduleRide -> BookHotel") {
+		t.Errorf("Expected the cycle to be logged, got:\n%s", all)
+	}
+}
+
+// TestRunAutoBlock tests that an AUTO block runs independent tasks concurrently and orders the rest by their data.
+func TestRunAutoBlock(t *testing.T) {
+	pr := parseScript(t, `START
+DATA flightInfo TYPE String ;
+DATA weather TYPE String ;
+PERM AGENT Flights DATA flightInfo ACCESS WRITE ;
+PERM AGENT Weather DATA weather ACCESS WRITE ;
+PERM AGENT Mailer DATA flightInfo ACCESS READ ;
+AUTO {
+    TASK ScheduleFlight AGENT Flights PARAMETERS (task="flight", message="slow", OUTPUT=flightInfo) ;
+    TASK SendItinerary AGENT Mailer PARAMETERS (task="itinerary", message=flightInfo) ;
+    TASK CheckWeather AGENT Weather PARAMETERS (task="weather", message="", OUTPUT=weather) ;
+}
+END`)
+	e, recorder := newRecordingExecutor(t, "Flights", "Mailer", "Weather")
+
+	l := logger.NewLogger()
+	if !scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatalf("Expected the run to succeed, got:\n%s", l.Text())
+	}
+	if expected := []string{"Weather", "Flights", "Mailer"}; !reflect.DeepEqual(recorder.called(), expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.called())
+	}
+	if got := recorder.payloads["Mailer"]["message"]; got != "Flights done" {
+		t.Errorf("Expected the itinerary to see the flight, got %q", got)
+	}
+
+	expected := "AUTO at 7:1: inferred dependencies:\nScheduleFlight\nSendItinerary after ScheduleFlight (reads flightInfo)\nCheckWeather\n"
+	if all := l.Text(); !strings.Contains(all, expected) {
+		t.Errorf("Expected log %q, got:\n%s", expected, all)
+	}
+}
@@ -0,0 +1,128 @@
+package scheduler_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"trace/package/logger"
+	"trace/package/scheduler"
+)
+
+// TestDeadline_Script tests that the script's DEADLINE times out every remaining task and fails the run.
+func TestDeadline_Script(t *testing.T) {
+	pr := parseScript(t, `START DEADLINE 30ms
+RUNCON {
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="slow") ;
+    TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") ;
+}
+TASK SendItinerary AGENT Mailer PARAMETERS (task="itinerary", message="") ;
+END`)
+	e, recorder := newRecordingExecutor(t, "Hotels", "Rides", "Mailer")
+
+	l := logger.NewLogger()
+	start := time.Now()
+	if scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Errorf("Expected the run to stop at its DEADLINE, but it took %s", elapsed)
+	}
+	if expected := []string{"Rides"}; !reflect.DeepEqual(recorder.called(), expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.called())
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		"Task BookHotel timed out while calling agent Hotels: the script exceeded its DEADLINE of 30ms",
+		"Task SendItinerary timed out before it started: the script exceeded its DEADLINE of 30ms",
+		"Status: Timed Out",
+		"Error: the script exceeded its DEADLINE of 30ms",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
+
+// TestDeadline_Block tests that a block's DEADLINE fails only that block, so the script carries on under CONTINUE.
+func TestDeadline_Block(t *testing.T) {
+	pr := parseScript(t, `START
+RUNSEQ DEADLINE 30ms {
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="slow") ;
+    TASK BookDinner AGENT Restaurants PARAMETERS (task="dinner", message="") ;
+}
+TASK SendItinerary AGENT Mailer PARAMETERS (task="itinerary", message="") ;
+END`)
+	e, recorder := newRecordingExecutor(t, "Hotels", "Restaurants", "Mailer")
+
+	l := logger.NewLogger()
+	if scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+	if expected := []string{"Mailer"}; !reflect.DeepEqual(recorder.called(), expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.called())
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		"Task BookHotel timed out while calling agent Hotels: RUNSEQ at 2:1 exceeded its DEADLINE of 30ms",
+		"Task BookDinner timed out before it started: RUNSEQ at 2:1 exceeded its DEADLINE of 30ms",
+		"Error: RUNSEQ at 2:1 exceeded its DEADLINE of 30ms",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
+
+// TestDeadline_NestedBlocks tests that the tasks of blocks that had not started when the DEADLINE passed are
+// reported Timed Out, and that the deadline is not repeated ahead of the errors that already carry it.
+func TestDeadline_NestedBlocks(t *testing.T) {
+	pr := parseScript(t, `START DEADLINE 30ms
+RUNSEQ {
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="slow") ;
+    RUNCON {
+        TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") ;
+        TASK BookDinner AGENT Restaurants PARAMETERS (task="dinner", message="") ;
+    }
+    TASK SendItinerary AGENT Mailer PARAMETERS (task="itinerary", message="") ;
+}
+END`)
+	e, _ := newRecordingExecutor(t, "Hotels", "Rides", "Restaurants", "Mailer")
+
+	l := logger.NewLogger()
+	result := scheduler.RunParentRequest(context.Background(), pr, e, l)
+	if result.Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+	if !strings.HasPrefix(result.Err.Error(), "task BookHotel") {
+		t.Errorf("Expected the error to start with the task in flight rather than repeat the deadline, got: %v", result.Err)
+	}
+
+	var got []string
+	for _, r := range result.Tasks {
+		got = append(got, r.Path+": "+r.Status.String())
+	}
+	expected := []string{
+		"RUNSEQ[0]/BookHotel: Timed Out",
+		"RUNSEQ[0]/RUNCON[1]/ScheduleRide: Timed Out",
+		"RUNSEQ[0]/RUNCON[1]/BookDinner: Timed Out",
+		"RUNSEQ[0]/SendItinerary: Timed Out",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected tasks %v, got %v", expected, got)
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		"Skipping RUNCON at 4:5: the script exceeded its DEADLINE of 30ms",
+		"Task ScheduleRide timed out before it started: the script exceeded its DEADLINE of 30ms",
+		"Task BookDinner timed out before it started: the script exceeded its DEADLINE of 30ms",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
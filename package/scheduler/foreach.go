@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"trace/package/parser"
+)
+
+// ListItems reads the named global data as a JSON array. String items are returned as-is and any other
+// item as its JSON text. An empty value is an empty list.
+func ListItems(globalData map[string]*parser.Data, name string) ([]string, error) {
+	data, found := globalData[name]
+	if !found {
+		return nil, fmt.Errorf("list '%s' is not declared", name)
+	}
+	data.Mu.Lock()
+	value := data.InitialValue
+	data.Mu.Unlock()
+
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, fmt.Errorf("list '%s' is not a JSON array: %w", name, err)
+	}
+
+	items := make([]string, len(raw))
+	for i, item := range raw {
+		var s string
+		if err := json.Unmarshal(item, &s); err == nil {
+			items[i] = s
+		} else {
+			items[i] = string(item)
+		}
+	}
+	return items, nil
+}
+
+// iterationScope returns the global data and permissions seen by one iteration of a FOREACH loop.
+// Shared data is unchanged; the loop variable is added with READ access for every agent in the body,
+// and the collect variable is a fresh value those agents can READ and WRITE.
+func iterationScope(forEach *parser.ForEachBlock, item string, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission) (map[string]*parser.Data, map[string]*parser.Permission) {
+	locals := []scopedData{
+		{Data: &parser.Data{DataName: forEach.Item, DataType: "String", InitialValue: item}, Access: []string{"READ"}},
+	}
+	if forEach.CollectVar != "" {
+		locals = append(locals, scopedData{Data: &parser.Data{DataName: forEach.CollectVar, DataType: "String"}, Access: []string{"READ", "WRITE"}})
+	}
+	return extendScope(forEach.Body, locals, globalData, globalPermissions)
+}
+
+// scopedData is data visible only inside a block, with the access granted to the agents in the block.
+type scopedData struct {
+	Data   *parser.Data
+	Access []string
+}
+
+// extendScope returns copies of the global data and permissions with the block-local data added.
+// Every agent used in body is granted the local data's access on top of its own permissions.
+func extendScope(body []parser.Statement, locals []scopedData, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission) (map[string]*parser.Data, map[string]*parser.Permission) {
+	data := make(map[string]*parser.Data, len(globalData)+len(locals))
+	for name, d := range globalData {
+		data[name] = d
+	}
+	for _, local := range locals {
+		data[local.Data.DataName] = local.Data
+	}
+
+	permissions := make(map[string]*parser.Permission, len(globalPermissions))
+	for agentName, perm := range globalPermissions {
+		permissions[agentName] = perm
+	}
+	for agentName := range bodyAgents(body) {
+		scopedPerm := &parser.Permission{AgentName: agentName, DataPermissions: make(map[string][]string)}
+		if perm, ok := globalPermissions[agentName]; ok {
+			scopedPerm.Span = perm.Span
+			scopedPerm.DataSpans = perm.DataSpans
+			for name, access := range perm.DataPermissions {
+				scopedPerm.DataPermissions[name] = access
+			}
+		}
+		for _, local := range locals {
+			scopedPerm.DataPermissions[local.Data.DataName] = local.Access
+		}
+		permissions[agentName] = scopedPerm
+	}
+	return data, permissions
+}
+
+// bodyAgents returns the names of the agents used by tasks and their compensations anywhere in the statements.
+func bodyAgents(statements []parser.Statement) map[string]bool {
+	agents := make(map[string]bool)
+	parser.InspectAll(statements, func(stmt parser.Statement) bool {
+		if t, ok := stmt.(*parser.Task); ok {
+			agents[t.AgentName] = true
+		}
+		return true
+	})
+	return agents
+}
+
+// writeCollected stores the collected values in the named global data as a JSON array.
+func writeCollected(globalData map[string]*parser.Data, name string, values []string) (string, error) {
+	data, found := globalData[name]
+	if !found {
+		return "", fmt.Errorf("collect target '%s' is not declared", name)
+	}
+	bytes, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	data.Mu.Lock()
+	data.InitialValue = string(bytes)
+	data.Mu.Unlock()
+	return string(bytes), nil
+}
@@ -0,0 +1,128 @@
+package scheduler_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"trace/package/executor"
+	"trace/package/logger"
+	"trace/package/scheduler"
+)
+
+// newFlightExecutor returns an executor with an in-process FlightGetter that answers with the destination it was sent.
+func newFlightExecutor(t *testing.T, delay time.Duration) *executor.Executor {
+	t.Helper()
+	return newInProcessExecutor(t, map[string]interface{}{"destination": "[[destination]]"}, func(agentName string, jsonPayload string) (string, error) {
+		var request map[string]string
+		if err := json.Unmarshal([]byte(jsonPayload), &request); err != nil {
+			return "", err
+		}
+		// Reverse the order in which concurrent iterations finish
+		if request["destination"] == "NYC" {
+			time.Sleep(delay)
+		}
+		return "flight to " + request["destination"], nil
+	}, "FlightGetter")
+}
+
+const forEachScript = `START
+DATA destinations TYPE List VALUE "[\"NYC\", \"LAX\", 3]" ;
+DATA flights TYPE List ;
+FOREACH destination IN destinations %s COLLECT flight INTO flights {
+    TASK SearchFlight AGENT FlightGetter PARAMETERS (destination=destination, OUTPUT=flight) ;
+}
+END`
+
+// TestRunForEachBlock tests that both variants run once per item and collect results in list order.
+func TestRunForEachBlock(t *testing.T) {
+	for _, mode := range []string{"", "CONCURRENT"} {
+		t.Run("mode="+mode, func(t *testing.T) {
+			pr := parseScript(t, strings.Replace(forEachScript, "%s", mode, 1))
+			l := logger.NewLogger()
+			if !scheduler.RunParentRequest(context.Background(), pr, newFlightExecutor(t, 50*time.Millisecond), l).Succeeded() {
+				t.Fatal("RunParentRequest failed")
+			}
+
+			expected := `["flight to NYC","flight to LAX","flight to 3"]`
+			if got := pr.GlobalData["flights"].InitialValue; got != expected {
+				t.Errorf("Expected flights %s, got %s", expected, got)
+			}
+			if _, leaked := pr.GlobalData["destination"]; leaked {
+				t.Error("Expected the loop variable not to be added to global data")
+			}
+		})
+	}
+}
+
+// TestRunForEachBlock_Concurrent tests that concurrent iterations overlap.
+func TestRunForEachBlock_Concurrent(t *testing.T) {
+	pr := parseScript(t, `START
+DATA packages TYPE List VALUE "[\"A\", \"B\", \"C\"]" ;
+FOREACH package IN packages CONCURRENT {
+    TASK TrackPackage AGENT PackageTracker PARAMETERS (tracking_number=package) ;
+}
+END`)
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	e := newInProcessExecutor(t, map[string]interface{}{"tracking_number": "[[tracking_number]]"}, func(agentName string, jsonPayload string) (string, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return "delivered", nil
+	}, "PackageTracker")
+
+	if !scheduler.RunParentRequest(context.Background(), pr, e, logger.NewLogger()).Succeeded() {
+		t.Fatal("RunParentRequest failed")
+	}
+	if maxRunning != 3 {
+		t.Errorf("Expected all 3 iterations to run at once, got at most %d", maxRunning)
+	}
+}
+
+// TestRunForEachBlock_InvalidList tests that a list that is not a JSON array fails the run.
+func TestRunForEachBlock_InvalidList(t *testing.T) {
+	pr := parseScript(t, `START
+DATA destinations TYPE List VALUE "NYC, LAX" ;
+FOREACH destination IN destinations {
+    TASK SearchFlight AGENT FlightGetter PARAMETERS (destination=destination) ;
+}
+END`)
+
+	l := logger.NewLogger()
+	if scheduler.RunParentRequest(context.Background(), pr, newFlightExecutor(t, 0), l).Succeeded() {
+		t.Fatal("Expected RunParentRequest to fail for an invalid list")
+	}
+}
+
+// TestListItems tests reading list data.
+func TestListItems(t *testing.T) {
+	pr := parseScript(t, `START
+DATA empty TYPE List ;
+DATA mixed TYPE List VALUE "[\"a\", 1, {\"b\": true}]" ;
+END`)
+
+	if items, err := scheduler.ListItems(pr.GlobalData, "empty"); err != nil || len(items) != 0 {
+		t.Errorf("Expected no items for an empty list, got %v, %v", items, err)
+	}
+	items, err := scheduler.ListItems(pr.GlobalData, "mixed")
+	if err != nil {
+		t.Fatalf("ListItems failed: %v", err)
+	}
+	if strings.Join(items, "|") != `a|1|{"b": true}` {
+		t.Errorf("Unexpected items: %q", items)
+	}
+	if _, err := scheduler.ListItems(pr.GlobalData, "missing"); err == nil {
+		t.Error("Expected error for an undeclared list, but got none")
+	}
+}
@@ -0,0 +1,101 @@
+package scheduler_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"trace/package/executor"
+	"trace/package/logger"
+	"trace/package/scheduler"
+)
+
+// newTrackerExecutor returns an executor with an in-process PackageTracker that reports each status in turn.
+func newTrackerExecutor(t *testing.T, statuses ...string) (*executor.Executor, *[]time.Time) {
+	t.Helper()
+	var mu sync.Mutex
+	var calls []time.Time
+	e := newInProcessExecutor(t, map[string]interface{}{}, func(agentName string, jsonPayload string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		status := statuses[len(statuses)-1]
+		if len(calls) < len(statuses) {
+			status = statuses[len(calls)]
+		}
+		calls = append(calls, time.Now())
+		return status, nil
+	}, "PackageTracker")
+	return e, &calls
+}
+
+const pollScript = `START
+DATA packageStatus TYPE String ;
+PERM AGENT PackageTracker DATA packageStatus ACCESS WRITE ;
+UNTIL packageStatus == "delivered" MAX %s {
+    TASK TrackPackage AGENT PackageTracker PARAMETERS (OUTPUT=packageStatus) ;
+}
+END`
+
+// TestRunLoopBlock_Until tests that an UNTIL loop polls until its condition holds, waiting between polls.
+func TestRunLoopBlock_Until(t *testing.T) {
+	pr := parseScript(t, strings.Replace(pollScript, "%s", "5 EVERY 20ms", 1))
+	e, calls := newTrackerExecutor(t, "in transit", "out for delivery", "delivered")
+
+	l := logger.NewLogger()
+	if !scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatal("RunParentRequest failed")
+	}
+
+	if len(*calls) != 3 {
+		t.Fatalf("Expected 3 polls, got %d", len(*calls))
+	}
+	for i := 1; i < len(*calls); i++ {
+		if gap := (*calls)[i].Sub((*calls)[i-1]); gap < 20*time.Millisecond {
+			t.Errorf("Expected polls at least 20ms apart, got %s between polls %d and %d", gap, i-1, i)
+		}
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		`UNTIL packageStatus == "delivered" at 4:1: iteration 0 of at most 5 ("" == "delivered")`,
+		`UNTIL packageStatus == "delivered" at 4:1: iteration 2 of at most 5 ("out for delivery" == "delivered")`,
+		`UNTIL packageStatus == "delivered" at 4:1 is true ("delivered" == "delivered"); stopping after 3 iterations`,
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
+
+// TestRunLoopBlock_Max tests that a loop still running after MAX iterations fails.
+func TestRunLoopBlock_Max(t *testing.T) {
+	pr := parseScript(t, strings.Replace(pollScript, "%s", "2", 1))
+	e, calls := newTrackerExecutor(t, "in transit")
+
+	if scheduler.RunParentRequest(context.Background(), pr, e, logger.NewLogger()).Succeeded() {
+		t.Fatal("Expected RunParentRequest to fail once the loop hit MAX")
+	}
+	if len(*calls) != 2 {
+		t.Errorf("Expected exactly 2 polls, got %d", len(*calls))
+	}
+}
+
+// TestRunLoopBlock_While tests that a WHILE loop whose condition is false never runs its body.
+func TestRunLoopBlock_While(t *testing.T) {
+	pr := parseScript(t, `START
+DATA packageStatus TYPE String VALUE "delivered" ;
+PERM AGENT PackageTracker DATA packageStatus ACCESS WRITE ;
+WHILE packageStatus != "delivered" MAX 3 {
+    TASK TrackPackage AGENT PackageTracker PARAMETERS (OUTPUT=packageStatus) ;
+}
+END`)
+	e, calls := newTrackerExecutor(t, "in transit")
+
+	if !scheduler.RunParentRequest(context.Background(), pr, e, logger.NewLogger()).Succeeded() {
+		t.Fatal("RunParentRequest failed")
+	}
+	if len(*calls) != 0 {
+		t.Errorf("Expected no polls, got %d", len(*calls))
+	}
+}
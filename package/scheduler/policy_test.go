@@ -0,0 +1,122 @@
+package scheduler_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"trace/package/logger"
+	"trace/package/scheduler"
+)
+
+const policyScript = `START %s
+RUNSEQ {
+    RUNCON {
+        TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="fail") ;
+        RUNSEQ {
+            TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="slow") ;
+            TASK BookDinner AGENT Restaurants PARAMETERS (task="dinner", message="") ;
+        }
+    }
+    TASK SendItinerary AGENT Mailer PARAMETERS (task="itinerary", message="") ;
+}
+END`
+
+// TestErrorPolicy_Stop tests that a failing branch cancels its siblings and the rest of the sequence.
+func TestErrorPolicy_Stop(t *testing.T) {
+	pr := parseScript(t, strings.Replace(policyScript, "%s", "ON ERROR STOP", 1))
+	e, recorder := newRecordingExecutor(t, "Rides", "Hotels", "Restaurants", "Mailer")
+
+	l := logger.NewLogger()
+	if scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+
+	// The hotel booking was already in flight when the ride failed, so it is cancelled rather than awaited
+	if expected := []string{"Rides"}; !reflect.DeepEqual(recorder.called(), expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.called())
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		"Task BookHotel cancelled while calling agent Hotels: stopped after a failure in a concurrent branch",
+		"RUNCON at 3:5 failed; skipping the rest of the sequence (1 statements)",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+	if strings.Contains(all, "Skipping TASK SendItinerary") {
+		t.Errorf("Expected the rest of the sequence to be skipped without running it, got:\n%s", all)
+	}
+}
+
+// TestErrorPolicy_Continue tests that by default every statement runs despite a failure.
+func TestErrorPolicy_Continue(t *testing.T) {
+	for _, header := range []string{"", "ON ERROR CONTINUE"} {
+		pr := parseScript(t, strings.Replace(policyScript, "%s", header, 1))
+		e, recorder := newRecordingExecutor(t, "Rides", "Hotels", "Restaurants", "Mailer")
+
+		if scheduler.RunParentRequest(context.Background(), pr, e, logger.NewLogger()).Succeeded() {
+			t.Fatalf("Header %q: expected the run to fail", header)
+		}
+		if expected := []string{"Rides", "Hotels", "Restaurants", "Mailer"}; !reflect.DeepEqual(recorder.calls, expected) {
+			t.Errorf("Header %q: expected calls %v, got %v", header, expected, recorder.calls)
+		}
+	}
+}
+
+// TestErrorPolicy_Block tests that a block's policy overrides the script's for everything inside it.
+func TestErrorPolicy_Block(t *testing.T) {
+	pr := parseScript(t, `START ON ERROR STOP
+RUNSEQ ON ERROR CONTINUE {
+    TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") ;
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="") ;
+}
+TASK SendItinerary AGENT Mailer PARAMETERS (task="itinerary", message="") ;
+END`)
+	e, recorder := newRecordingExecutor(t, "Hotels", "Mailer")
+
+	if scheduler.RunParentRequest(context.Background(), pr, e, logger.NewLogger()).Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+	if expected := []string{"Hotels"}; !reflect.DeepEqual(recorder.calls, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.calls)
+	}
+}
+
+// TestRunParentRequest_Deadline tests that a context deadline times out the task in flight and every task after it.
+func TestRunParentRequest_Deadline(t *testing.T) {
+	pr := parseScript(t, `START
+TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="slow") ;
+TASK SendItinerary AGENT Mailer PARAMETERS (task="itinerary", message="") ;
+END`)
+	e, recorder := newRecordingExecutor(t, "Hotels", "Mailer")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	l := logger.NewLogger()
+	start := time.Now()
+	if scheduler.RunParentRequest(ctx, pr, e, l).Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 80*time.Millisecond {
+		t.Errorf("Expected the run to stop at its deadline, but it took %s", elapsed)
+	}
+	if calls := recorder.called(); len(calls) != 0 {
+		t.Errorf("Expected no agent to finish, got %v", calls)
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		"Task BookHotel timed out while calling agent Hotels: context deadline exceeded",
+		"Task SendItinerary timed out before it started: context deadline exceeded",
+		"Status: Timed Out",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
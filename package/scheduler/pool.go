@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"trace/package/logger"
+	"trace/package/parser"
+)
+
+// semaphore limits how many holders run at once. A nil semaphore has no limit.
+type semaphore chan struct{}
+
+// newSemaphore creates a semaphore with n slots, or nil if n is not positive.
+func newSemaphore(n int) semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(semaphore, n)
+}
+
+// acquire takes a slot, waiting until one is free or ctx is done. It reports how long it waited, zero if a
+// slot was free straight away, and whether it got one.
+func (s semaphore) acquire(ctx context.Context) (time.Duration, bool) {
+	if s == nil {
+		return 0, true
+	}
+	select {
+	case s <- struct{}{}:
+		return 0, true
+	default:
+	}
+
+	start := time.Now()
+	select {
+	case s <- struct{}{}:
+		return time.Since(start), true
+	case <-ctx.Done():
+		return time.Since(start), false
+	}
+}
+
+// release gives back a slot taken with acquire.
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// WorkerPool bounds how many tasks call their agents at once, across every block of a run: in total, and
+// for each agent. Tasks wait in the pool until they get a slot. A nil pool has no limits.
+type WorkerPool struct {
+	workers     semaphore
+	maxParallel int
+	agents      map[string]semaphore
+	agentLimits map[string]int
+}
+
+// NewWorkerPool creates a pool that runs at most maxParallel tasks at once, and at most agentLimits[name]
+// tasks at once for each agent named. Zero or less means no limit.
+func NewWorkerPool(maxParallel int, agentLimits map[string]int) *WorkerPool {
+	pool := &WorkerPool{
+		workers:     newSemaphore(maxParallel),
+		maxParallel: maxParallel,
+		agents:      make(map[string]semaphore, len(agentLimits)),
+		agentLimits: agentLimits,
+	}
+	for name, limit := range agentLimits {
+		pool.agents[name] = newSemaphore(limit)
+	}
+	return pool
+}
+
+// acquireWorker waits until the pool lets the task call its agent, logging how long the task was queued,
+// and returns the function that gives its slots back. The agent's slot is taken before a worker, so that
+// tasks queued for a busy agent do not keep tasks for other agents waiting. The slot is that of the agent
+// the executor resolves the task to, which is another agent of the same type if the named one is down. If
+// ctx is done first, no slot is kept and the task is left for the executor to cancel.
+func (r *Runner) acquireWorker(ctx context.Context, t *parser.Task) func() {
+	if r.pool == nil {
+		return func() {}
+	}
+
+	agentName := t.AgentName
+	if a, err := r.Executor.ResolveAgent(t.AgentName); err == nil {
+		agentName = a.GetName()
+	}
+	agentSlots := r.pool.agents[agentName]
+	waited, ok := agentSlots.acquire(ctx)
+	if !ok {
+		return func() {}
+	}
+	if waited > 0 {
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("Task %s waited %s for agent %s (limit %d)", t.TaskName, waited.Round(time.Millisecond), agentName, r.pool.agentLimits[agentName])))
+	}
+
+	waited, ok = r.pool.workers.acquire(ctx)
+	if !ok {
+		agentSlots.release()
+		return func() {}
+	}
+	if waited > 0 {
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("Task %s waited %s for one of %d workers", t.TaskName, waited.Round(time.Millisecond), r.pool.maxParallel)))
+	}
+	return func() {
+		r.pool.workers.release()
+		agentSlots.release()
+	}
+}
+
+// acquireSlot waits for one of the max slots of a block, logging how long the statement was queued, and
+// returns the function that gives the slot back. If ctx is done first, no slot is kept.
+func (r *Runner) acquireSlot(ctx context.Context, slots semaphore, max int, stmt parser.Statement, block string) func() {
+	waited, ok := slots.acquire(ctx)
+	if !ok {
+		return func() {}
+	}
+	if waited > 0 {
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s waited %s for one of the %d slots of %s", describeStatement(stmt), waited.Round(time.Millisecond), max, block)))
+	}
+	return slots.release
+}
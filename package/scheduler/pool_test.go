@@ -0,0 +1,133 @@
+package scheduler_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"trace/package/agent"
+	"trace/package/logger"
+	"trace/package/scheduler"
+)
+
+// TestRunConBlock_Max tests that a RUNCON block with MAX runs no more of its statements at once, and logs
+// the statements that had to wait.
+func TestRunConBlock_Max(t *testing.T) {
+	pr := parseScript(t, `START
+RUNCON MAX 2 {
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="slow") ;
+    TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="slow") ;
+    TASK ScheduleFlight AGENT Flights PARAMETERS (task="flight", message="slow") ;
+}
+END`)
+	e, recorder := newRecordingExecutor(t, "Hotels", "Rides", "Flights")
+
+	l := logger.NewLogger()
+	start := time.Now()
+	if !scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatalf("Expected the run to succeed, got logs:\n%s", l.Text())
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected the third task to wait for a slot, but the block took %s", elapsed)
+	}
+	if len(recorder.called()) != 3 {
+		t.Errorf("Expected every task to run, got %v", recorder.called())
+	}
+	if got := strings.Count(l.Text(), "for one of the 2 slots of RUNCON at 2:1"); got != 1 {
+		t.Errorf("Expected one task to be logged waiting for a slot, got %d in:\n%s", got, l.Text())
+	}
+}
+
+// TestWorkerPool tests that the pool limits tasks across blocks, in total and for each agent.
+func TestWorkerPool(t *testing.T) {
+	pr := parseScript(t, `START
+RUNCON {
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="slow") ;
+    RUNSEQ {
+        TASK UpgradeHotel AGENT Hotels PARAMETERS (task="upgrade", message="slow") ;
+    }
+    RUNCON {
+        TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="slow") ;
+        TASK ScheduleFlight AGENT Flights PARAMETERS (task="flight", message="slow") ;
+    }
+}
+END`)
+	e, recorder := newRecordingExecutor(t, "Hotels", "Rides", "Flights")
+
+	l := logger.NewLogger()
+	pool := scheduler.NewWorkerPool(2, map[string]int{"Hotels": 1})
+	start := time.Now()
+	if !scheduler.RunParentRequestWithOptions(context.Background(), pr, e, l, scheduler.Options{Pool: pool}).Succeeded() {
+		t.Fatalf("Expected the run to succeed, got logs:\n%s", l.Text())
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected four slow tasks on two workers to take two rounds, took %s", elapsed)
+	}
+	if len(recorder.called()) != 4 {
+		t.Errorf("Expected every task to run, got %v", recorder.called())
+	}
+
+	all := l.Text()
+	if got := strings.Count(all, "for agent Hotels (limit 1)"); got != 1 {
+		t.Errorf("Expected one hotel task to wait for the agent, got %d in:\n%s", got, all)
+	}
+	if !strings.Contains(all, "for one of 2 workers") {
+		t.Errorf("Expected a task to wait for a worker, got:\n%s", all)
+	}
+}
+
+// TestWorkerPool_Cancelled tests that a task still queued when the run is cancelled is cancelled without
+// calling its agent.
+func TestWorkerPool_Cancelled(t *testing.T) {
+	pr := parseScript(t, `START
+RUNCON {
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="slow") ;
+    TASK UpgradeHotel AGENT Hotels PARAMETERS (task="upgrade", message="slow") ;
+}
+END`)
+	e, recorder := newRecordingExecutor(t, "Hotels")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	l := logger.NewLogger()
+	pool := scheduler.NewWorkerPool(1, nil)
+	if scheduler.RunParentRequestWithOptions(ctx, pr, e, l, scheduler.Options{Pool: pool}).Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+	time.Sleep(150 * time.Millisecond)
+	if got := recorder.called(); len(got) > 1 {
+		t.Errorf("Expected the queued task not to call its agent, got %v", got)
+	}
+	if all := l.Text(); !strings.Contains(all, "timed out before it started") {
+		t.Errorf("Expected the queued task to time out before it started, got:\n%s", all)
+	}
+}
+
+// TestWorkerPool_Fallback tests that a task whose agent is down takes a slot of the agent it falls back
+// to, so the fallback's limit holds however its tasks name their agent.
+func TestWorkerPool_Fallback(t *testing.T) {
+	pr := parseScript(t, `START
+RUNCON {
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="slow") ;
+    TASK UpgradeHotel AGENT Backup PARAMETERS (task="upgrade", message="slow") ;
+}
+END`)
+	e, recorder := newRecordingExecutor(t, "Hotels", "Backup")
+	e.Registry.GetByName("Hotels").SetAvailability(agent.Unavailable)
+
+	l := logger.NewLogger()
+	pool := scheduler.NewWorkerPool(0, map[string]int{"Backup": 1})
+	start := time.Now()
+	if !scheduler.RunParentRequestWithOptions(context.Background(), pr, e, l, scheduler.Options{Pool: pool}).Succeeded() {
+		t.Fatalf("Expected the run to succeed, got logs:\n%s", l.Text())
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected both tasks to take turns on the backup agent, took %s", elapsed)
+	}
+	if got := recorder.called(); len(got) != 2 || got[0] != "Backup" || got[1] != "Backup" {
+		t.Errorf("Expected both tasks to call the backup agent, got %v", got)
+	}
+	if all := l.Text(); strings.Count(all, "for agent Backup (limit 1)") != 1 {
+		t.Errorf("Expected one task to wait for the backup agent, got:\n%s", all)
+	}
+}
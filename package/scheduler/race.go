@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"trace/package/logger"
+	"trace/package/parser"
+)
+
+// ErrQuorumReached is the cause of the children of a RUNANY or RUNQUORUM block cancelled because enough of
+// their siblings had already succeeded.
+var ErrQuorumReached = errors.New("cancelled after enough other branches succeeded")
+
+// stagedData is a private copy of the global data for one child of a RUNANY or RUNQUORUM block, so that
+// only the writes of the children that win are kept.
+type stagedData struct {
+	data     map[string]*parser.Data
+	original map[string]string // Values at the time the copy was made
+}
+
+// stage copies every piece of global data for one child.
+func stage(globalData map[string]*parser.Data) *stagedData {
+	staged := &stagedData{
+		data:     make(map[string]*parser.Data, len(globalData)),
+		original: make(map[string]string, len(globalData)),
+	}
+	for name, d := range globalData {
+		d.Mu.Lock()
+		staged.data[name] = &parser.Data{DataName: d.DataName, DataType: d.DataType, InitialValue: d.InitialValue, Span: d.Span}
+		staged.original[name] = d.InitialValue
+		d.Mu.Unlock()
+	}
+	return staged
+}
+
+// commit writes the data the child changed back to the global data and returns the names written, sorted.
+func (s *stagedData) commit(globalData map[string]*parser.Data) []string {
+	var written []string
+	for name, d := range s.data {
+		d.Mu.Lock()
+		value := d.InitialValue
+		d.Mu.Unlock()
+		if value == s.original[name] {
+			continue
+		}
+		target := globalData[name]
+		target.Mu.Lock()
+		target.InitialValue = value
+		target.Mu.Unlock()
+		written = append(written, name)
+	}
+	sort.Strings(written)
+	return written
+}
+
+// RunAnyBlock runs the children of a RUNANY or RUNQUORUM block concurrently, each against its own copy of
+// the global data. The first children to succeed, up to the quorum, win: their writes are committed and
+// their compensations kept, and the children still running are cancelled. Children whose work is discarded
+// are compensated straight away. The block fails once the quorum can no longer be reached.
+func (r *Runner) RunAnyBlock(ctx context.Context, anyBlock *parser.RunAnyBlock) error {
+	description := describeStatement(anyBlock)
+	return r.runWithDeadline(ctx, description, anyBlock.Deadline, func(ctx context.Context) error {
+		return r.runRace(ctx, anyBlock, description)
+	})
+}
+
+// runRace runs the race of a RunAnyBlock; see RunAnyBlock.
+func (r *Runner) runRace(ctx context.Context, anyBlock *parser.RunAnyBlock, description string) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	type result struct {
+		i   int
+		err error
+	}
+	n := len(anyBlock.Statements)
+	children := make([]*Runner, n)
+	staged := make([]*stagedData, n)
+	results := make(chan result, n)
+	for i, stmt := range anyBlock.Statements {
+		staged[i] = stage(r.GlobalData)
+		child := r.withScope(staged[i].data, r.Permissions).at(pathSegment(stmt, i))
+		child.compensations = &compensationStack{}
+		children[i] = child
+		go func(i int, stmt parser.Statement) {
+			results <- result{i: i, err: child.RunStatement(ctx, stmt)}
+		}(i, stmt)
+	}
+	r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: racing %d statements for %d successes", description, n, anyBlock.Quorum)))
+
+	// Results are handled in the order the children finish
+	unreachable := fmt.Errorf("%s can no longer reach %d successes", description, anyBlock.Quorum)
+	var winners int
+	var failures []error
+	for range anyBlock.Statements {
+		res := <-results
+		stmt := describeStatement(anyBlock.Statements[res.i])
+		child := children[res.i]
+
+		switch {
+		case res.err == nil && winners < anyBlock.Quorum:
+			winners++
+			written := staged[res.i].commit(r.GlobalData)
+			r.compensations.push(child.compensations.drain()...)
+			r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: %s succeeded (%d of %d needed); committed [%s]", description, stmt, winners, anyBlock.Quorum, strings.Join(written, ", "))))
+			if winners == anyBlock.Quorum {
+				cancel(ErrQuorumReached)
+			}
+			continue
+		case res.err == nil:
+			r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: %s succeeded after the quorum was reached; discarding its result", description, stmt)))
+		case errors.Is(res.err, ErrQuorumReached), errors.Is(res.err, unreachable):
+			r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: %s was cancelled: %v", description, stmt, context.Cause(ctx))))
+		default:
+			failures = append(failures, res.err)
+			r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: %s failed: %v", description, stmt, res.err)))
+			if winners < anyBlock.Quorum && n-len(failures) < anyBlock.Quorum {
+				cancel(unreachable)
+			}
+		}
+
+		// Undo whatever the child completed before it lost
+		if err := child.Compensate(ctx); err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	if winners < anyBlock.Quorum {
+		err := fmt.Errorf("%s: %d of %d statements succeeded, %d needed: %w", description, winners, n, anyBlock.Quorum, errors.Join(failures...))
+		r.Logger.AddLog(logger.NewLog("Error: " + err.Error()))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package scheduler_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"trace/package/logger"
+	"trace/package/scheduler"
+)
+
+const raceScript = `START
+DATA weather TYPE String ;
+PERM AGENT WeatherA DATA weather ACCESS WRITE ;
+PERM AGENT WeatherB DATA weather ACCESS WRITE ;
+PERM AGENT WeatherC DATA weather ACCESS WRITE ;
+%s {
+    TASK CheckWeatherA AGENT WeatherA PARAMETERS (task="a", message="slow", OUTPUT=weather) ;
+    TASK CheckWeatherB AGENT WeatherB PARAMETERS (task="b", message="%s", OUTPUT=weather) ;
+    TASK CheckWeatherC AGENT WeatherC PARAMETERS (task="c", message="fail", OUTPUT=weather) ;
+}
+END`
+
+// TestRunAnyBlock tests that the first child to succeed wins, its output is committed and the others are cancelled.
+func TestRunAnyBlock(t *testing.T) {
+	// The winner takes long enough for the slow task to be calling its agent when it is cancelled
+	pr := parseScript(t, strings.NewReplacer("%s {", "RUNANY {", `"%s"`, `"late"`).Replace(raceScript))
+	e, recorder := newRecordingExecutor(t, "WeatherA", "WeatherB", "WeatherC")
+
+	l := logger.NewLogger()
+	if !scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatalf("Expected the run to succeed, got:\n%s", l.Text())
+	}
+	if expected := []string{"WeatherB"}; !reflect.DeepEqual(recorder.called(), expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.called())
+	}
+	if got := pr.GlobalData["weather"].InitialValue; got != "WeatherB done" {
+		t.Errorf("Expected the winner's output to be committed, got %q", got)
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		"RUNANY at 6:1: TASK CheckWeatherB at 8:5 succeeded (1 of 1 needed); committed [weather]",
+		"RUNANY at 6:1: TASK CheckWeatherA at 7:5 was cancelled: cancelled after enough other branches succeeded",
+		"RUNANY at 6:1: TASK CheckWeatherC at 9:5 was cancelled: cancelled after enough other branches succeeded",
+		"Task CheckWeatherA cancelled while calling agent WeatherA",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
+
+// TestRunQuorumBlock tests that RUNQUORUM waits for enough successes and ignores failures it can afford.
+func TestRunQuorumBlock(t *testing.T) {
+	pr := parseScript(t, strings.NewReplacer("%s {", "RUNQUORUM 2 {", `"%s"`, `""`).Replace(raceScript))
+	e, recorder := newRecordingExecutor(t, "WeatherA", "WeatherB", "WeatherC")
+
+	l := logger.NewLogger()
+	if !scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatalf("Expected the run to succeed, got:\n%s", l.Text())
+	}
+	if expected := []string{"WeatherB", "WeatherC", "WeatherA"}; !reflect.DeepEqual(recorder.called(), expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.called())
+	}
+	if got := pr.GlobalData["weather"].InitialValue; got != "WeatherA done" {
+		t.Errorf("Expected the last winner's output to be committed, got %q", got)
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		"RUNQUORUM at 6:1: racing 3 statements for 2 successes",
+		"RUNQUORUM at 6:1: TASK CheckWeatherC at 9:5 failed: task CheckWeatherC failed: error calling agent: WeatherC is overloaded",
+		"RUNQUORUM at 6:1: TASK CheckWeatherA at 7:5 succeeded (2 of 2 needed); committed [weather]",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
+
+// TestRunQuorumBlock_Unreachable tests that the block fails, without waiting for the rest, once too many children have failed.
+func TestRunQuorumBlock_Unreachable(t *testing.T) {
+	pr := parseScript(t, strings.NewReplacer("%s {", "RUNQUORUM 2 {", `"%s"`, `"fail"`).Replace(raceScript))
+	e, _ := newRecordingExecutor(t, "WeatherA", "WeatherB", "WeatherC")
+
+	l := logger.NewLogger()
+	if scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+	if got := pr.GlobalData["weather"].InitialValue; got != "" {
+		t.Errorf("Expected no output to be committed, got %q", got)
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		"RUNQUORUM at 6:1: TASK CheckWeatherA at 7:5 was cancelled: RUNQUORUM at 6:1 can no longer reach 2 successes",
+		"Error: RUNQUORUM at 6:1: 0 of 3 statements succeeded, 2 needed",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
+
+// TestRunAnyBlock_CompensatesLosers tests that tasks completed by a losing child are compensated straight away.
+func TestRunAnyBlock_CompensatesLosers(t *testing.T) {
+	pr := parseScript(t, `START
+RUNANY {
+    RUNSEQ {
+        TASK HoldRoomA AGENT HotelA PARAMETERS (task="hold", message="")
+            COMPENSATE WITH TASK ReleaseRoomA AGENT HotelA PARAMETERS (task="release", message="") ;
+        TASK ConfirmRoomA AGENT HotelA PARAMETERS (task="confirm", message="slow") ;
+    }
+    TRY {
+        TASK ProbeHotelB AGENT HotelB PARAMETERS (task="probe", message="fail") ;
+    } CATCH {
+        TASK BookRoomB AGENT HotelB PARAMETERS (task="book", message="") ;
+    }
+}
+END`)
+	e, recorder := newRecordingExecutor(t, "HotelA", "HotelB")
+
+	l := logger.NewLogger()
+	if !scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatalf("Expected the run to succeed, got:\n%s", l.Text())
+	}
+	if expected := []string{"HotelA", "HotelB", "HotelB", "HotelA"}; !reflect.DeepEqual(recorder.called(), expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.called())
+	}
+	recorder.mu.Lock()
+	released := recorder.payloads["HotelA"]["task"]
+	recorder.mu.Unlock()
+	if released != "release" {
+		t.Errorf("Expected the hold on room A to be released, got %q", released)
+	}
+	if all := l.Text(); !strings.Contains(all, "Compensation 1 of 1: ReleaseRoomA undid HoldRoomA") {
+		t.Errorf("Expected the hold to be compensated, got:\n%s", all)
+	}
+}
@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"trace/package/parser"
+	"trace/package/task"
+)
+
+// RunResult is what happened in a run of a script, for programs that embed the scheduler.
+type RunResult struct {
+	Err      error             // Why the run failed, including failed compensations; nil if it succeeded
+	Tasks    []TaskResult      // Every task that was started or cancelled, compensations included, in the order they finished
+	Data     map[string]string // The value of every piece of global data when the run ended
+	Started  time.Time
+	Finished time.Time
+}
+
+// Succeeded reports whether the run completed without errors.
+func (r *RunResult) Succeeded() bool {
+	return r.Err == nil
+}
+
+// Failures returns the tasks that failed, were cancelled or timed out, in the order they finished.
+func (r *RunResult) Failures() []TaskResult {
+	var failures []TaskResult
+	for _, t := range r.Tasks {
+		if t.Err != nil {
+			failures = append(failures, t)
+		}
+	}
+	return failures
+}
+
+// TaskResult is what happened to one run of a task. A task in a loop has one result per iteration.
+type TaskResult struct {
+	Path     string // Where the task is in the script, such as RUNSEQ[0]/RUNCON[1]/RUNSEQ_1/TrackPackage
+	Name     string
+	Agent    string      // The agent the script names
+	Owner    string      // ID of the agent that ran the task, which differs from Agent's after a fallback
+	Status   task.Status // Finished, Failed, Cancelled or Timed Out
+	Attempts int         // Calls made to the agent, counting retries
+	Output   string      // Value written to the task's OUTPUT data; empty if it has none or did not finish
+	Results  []string    // Every response the task recorded
+	Err      error       // Why the task did not finish; nil if it did
+	Started  time.Time
+	Finished time.Time
+}
+
+// Duration returns how long the task ran, including retries but not time spent queued for a worker.
+func (t TaskResult) Duration() time.Duration {
+	return t.Finished.Sub(t.Started)
+}
+
+// String returns the task's path with its status, and the error if it did not finish.
+func (t TaskResult) String() string {
+	attempts := fmt.Sprintf("%d attempts", t.Attempts)
+	if t.Attempts == 1 {
+		attempts = "1 attempt"
+	}
+	if t.Err != nil {
+		return fmt.Sprintf("%s: %s after %s: %v", t.Path, t.Status, attempts, t.Err)
+	}
+	return fmt.Sprintf("%s: %s after %s in %s", t.Path, t.Status, attempts, t.Duration().Round(time.Millisecond))
+}
+
+// taskResults collects the results of tasks as they finish. Concurrent branches add to the same list.
+type taskResults struct {
+	list []TaskResult
+	mu   sync.Mutex
+}
+
+// add records the result of a task that just finished.
+func (s *taskResults) add(result TaskResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = append(s.list, result)
+}
+
+// all returns a copy of the results recorded so far.
+func (s *taskResults) all() []TaskResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TaskResult(nil), s.list...)
+}
+
+// at returns a copy of the runner for the statement reached through the given path segment.
+func (r *Runner) at(segment string) *Runner {
+	scoped := *r
+	if r.path == "" {
+		scoped.path = segment
+	} else {
+		scoped.path = r.path + "/" + segment
+	}
+	return &scoped
+}
+
+// pathSegment names the statement at index i of a list in block paths: a task by its name and a block by
+// its keyword and index, such as RUNSEQ[0].
+func pathSegment(stmt parser.Statement, i int) string {
+	if t, isTask := stmt.(*parser.Task); isTask {
+		return t.TaskName
+	}
+	return fmt.Sprintf("%s[%d]", statementKeyword(stmt), i)
+}
+
+// snapshot copies the current value of every piece of global data.
+func snapshot(globalData map[string]*parser.Data) map[string]string {
+	values := make(map[string]string, len(globalData))
+	for name, d := range globalData {
+		d.Mu.Lock()
+		values[name] = d.InitialValue
+		d.Mu.Unlock()
+	}
+	return values
+}
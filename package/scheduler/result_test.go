@@ -0,0 +1,85 @@
+package scheduler_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"trace/package/logger"
+	"trace/package/scheduler"
+	"trace/package/task"
+)
+
+// TestRunResult tests that the result of a run reports every task with its block path, status, attempts and
+// output, and the data the run ended with.
+func TestRunResult(t *testing.T) {
+	pr := parseScript(t, `START
+DATA flightInfo TYPE String ;
+DATA cities TYPE List VALUE "[\"Paris\", \"Rome\"]" ;
+PERM AGENT Flights DATA flightInfo ACCESS WRITE ;
+RUNSEQ {
+    TASK ScheduleFlight AGENT Flights PARAMETERS (task="flight", message="", OUTPUT=flightInfo) ;
+    RUNCON {
+        RUNSEQ {
+            TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="")
+                COMPENSATE WITH TASK CancelHotel AGENT Hotels PARAMETERS (task="cancel", message="") ;
+        }
+        RUNSEQ {
+            TASK TrackPackage AGENT Packages PARAMETERS (task="package", message="fail") RETRY 1 ;
+        }
+    }
+}
+FOREACH city IN cities {
+    TASK CheckWeather AGENT Weather PARAMETERS (task=city, message="") ;
+}
+END`)
+	e, _ := newRecordingExecutor(t, "Flights", "Hotels", "Packages", "Weather")
+
+	result := scheduler.RunParentRequest(context.Background(), pr, e, logger.NewLogger())
+	if result.Succeeded() {
+		t.Fatal("Expected the run to fail")
+	}
+
+	type summary struct {
+		Path     string
+		Status   task.Status
+		Attempts int
+		Output   string
+	}
+	var got []summary
+	for _, r := range result.Tasks {
+		got = append(got, summary{Path: r.Path, Status: r.Status, Attempts: r.Attempts, Output: r.Output})
+		if r.Finished.Before(r.Started) || r.Started.Before(result.Started) || result.Finished.Before(r.Finished) {
+			t.Errorf("%s: expected the task's times to fall within the run's, got %s to %s", r.Path, r.Started, r.Finished)
+		}
+	}
+	expected := []summary{
+		{"RUNSEQ[0]/ScheduleFlight", task.Finished, 1, "Flights done"},
+		{"RUNSEQ[0]/RUNCON[1]/RUNSEQ_0/BookHotel", task.Finished, 1, ""},
+		{"RUNSEQ[0]/RUNCON[1]/RUNSEQ_1/TrackPackage", task.Failed, 2, ""},
+		{"FOREACH[1]/#0/CheckWeather", task.Finished, 1, ""},
+		{"FOREACH[1]/#1/CheckWeather", task.Finished, 1, ""},
+		{"RUNSEQ[0]/RUNCON[1]/RUNSEQ_0/BookHotel/COMPENSATE/CancelHotel", task.Finished, 1, ""},
+	}
+	// The branches of the RUNCON may finish in either order
+	if len(got) == len(expected) && got[1].Path != expected[1].Path {
+		got[1], got[2] = got[2], got[1]
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected task results:\nExpected: %+v\nGot:      %+v", expected, got)
+	}
+
+	failures := result.Failures()
+	if len(failures) != 1 || failures[0].Name != "TrackPackage" || !strings.Contains(failures[0].Err.Error(), "Packages is overloaded") {
+		t.Fatalf("Expected TrackPackage to be the only failure, got %v", failures)
+	}
+	if !strings.HasPrefix(failures[0].String(), "RUNSEQ[0]/RUNCON[1]/RUNSEQ_1/TrackPackage: Failed after 2 attempts: task TrackPackage failed") {
+		t.Errorf("Expected the failure to start with its path and status, got %q", failures[0])
+	}
+	if got := result.Data["flightInfo"]; got != "Flights done" {
+		t.Errorf("Expected the final data to hold the flight, got %q", got)
+	}
+	if _, ok := result.Data["cities"]; !ok || len(result.Data) != 2 {
+		t.Errorf("Expected a snapshot of both pieces of data, got %v", result.Data)
+	}
+}
@@ -1,98 +1,649 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math/rand"
+	"strings"
 	"sync"
 	"time"
+	"trace/package/analysis"
 	"trace/package/executor"
 	"trace/package/logger"
 	"trace/package/parser"
 )
 
-// RunParentRequest schedules and runs the AICL parent request script
-func RunParentRequest(p *parser.ParentRequest, l *logger.Logger) bool {
-	errors := []string{}
-	statements := p.Statements
-	globalData := p.GlobalData
-	globalPermissions := p.Permissions
+// Options configure how a script is run.
+type Options struct {
+	Pool *WorkerPool // Limits on how many tasks run at once, shared by every block; nil for none
+}
+
+// RunParentRequest schedules and runs the AICL parent request script and returns what happened to each
+// task and the data the run ended with. Cancelling ctx, or reaching its deadline or the script's DEADLINE,
+// stops the run: tasks in flight are cancelled and the rest are skipped
+func RunParentRequest(ctx context.Context, p *parser.ParentRequest, e *executor.Executor, l *logger.Logger) *RunResult {
+	return RunParentRequestWithOptions(ctx, p, e, l, Options{})
+}
 
-	for _, stmt := range statements {
-		RunStatement(stmt, globalData, globalPermissions, l, &errors)
+// RunParentRequestWithOptions runs the script like RunParentRequest, with its tasks sharing the worker pool
+// of the options
+func RunParentRequestWithOptions(ctx context.Context, p *parser.ParentRequest, e *executor.Executor, l *logger.Logger, options Options) *RunResult {
+	result := &RunResult{Started: time.Now()}
+	r := NewRunner(p, e, l)
+	r.pool = options.Pool
+
+	err := r.runWithDeadline(ctx, "the script", p.Deadline, func(ctx context.Context) error {
+		return r.runSequence(ctx, p.Statements)
+	})
+
+	// Undo the tasks that completed before the run failed
+	if err != nil {
+		if compensateErr := r.Compensate(ctx); compensateErr != nil {
+			err = errors.Join(err, compensateErr)
+		}
 	}
 
-	if len(errors) != 0 {
-		fmt.Println("Errors occurred during runtime:", errors)
-		return false
+	result.Err = err
+	result.Tasks = r.results.all()
+	result.Data = snapshot(p.GlobalData)
+	result.Finished = time.Now()
+	return result
+}
+
+// TaskError reports a failed task, so that CATCH blocks can tell which task failed.
+type TaskError struct {
+	TaskName  string
+	AgentName string
+	Err       error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %s failed: %v", e.TaskName, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// DeadlineError is the cause of tasks stopped because the script or a block ran past its DEADLINE. It
+// matches context.DeadlineExceeded, so the tasks are marked Timed Out.
+type DeadlineError struct {
+	Scope    string // What the DEADLINE was set on, such as "the script" or "RUNSEQ at 3:1"
+	Deadline time.Duration
+}
+
+func (e *DeadlineError) Error() string {
+	return fmt.Sprintf("%s exceeded its DEADLINE of %s", e.Scope, e.Deadline)
+}
+
+func (e *DeadlineError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// DependencyError is the error of a statement skipped because a statement it runs AFTER failed.
+type DependencyError struct {
+	Dependency string
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("%s failed", e.Dependency)
+}
+
+// ErrStopped is the error of statements skipped because a concurrent sibling failed under ON ERROR STOP.
+var ErrStopped = errors.New("stopped after a failure in a concurrent branch")
+
+// Runner runs statements against the global data and permissions in scope. Each Run method returns the
+// failures of the statement and everything nested in it, or nil if it succeeded.
+type Runner struct {
+	GlobalData  map[string]*parser.Data
+	Permissions map[string]*parser.Permission
+	Executor    *executor.Executor
+	Logger      *logger.Logger
+	FailFast    bool // Stop at the first failure, skipping the rest of the sequence and cancelling concurrent siblings
+
+	compensations *compensationStack
+	results       *taskResults
+	pool          *WorkerPool // Shared by the whole run; nil for no limits
+	path          string      // Block path of the statement being run, such as RUNSEQ[0]/RUNCON[1]
+}
+
+// NewRunner creates a Runner for the script that follows its ON ERROR policy, continuing after failures by default.
+func NewRunner(p *parser.ParentRequest, e *executor.Executor, l *logger.Logger) *Runner {
+	return &Runner{
+		GlobalData:  p.GlobalData,
+		Permissions: p.Permissions,
+		Executor:    e,
+		Logger:      l,
+		FailFast:    p.OnError == parser.OnErrorStop,
+
+		compensations: &compensationStack{},
+		results:       &taskResults{},
+	}
+}
+
+// withScope returns a copy of the runner that sees the given data and permissions.
+func (r *Runner) withScope(globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission) *Runner {
+	scoped := *r
+	scoped.GlobalData = globalData
+	scoped.Permissions = globalPermissions
+	return &scoped
+}
+
+// withPolicy returns the runner to use inside a block with the given ON ERROR policy. An empty policy
+// keeps the runner's own.
+func (r *Runner) withPolicy(policy string) *Runner {
+	if policy == "" {
+		return r
 	}
-	return true
+	scoped := *r
+	scoped.FailFast = policy == parser.OnErrorStop
+	return &scoped
 }
 
-// RunStatement handles the execution of a single statement
-func RunStatement(stmt interface{}, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission, l *logger.Logger, errors *[]string) {
+// RunStatement handles the execution of a single statement. Blocks reached after ctx is cancelled are
+// skipped, and tasks are marked Cancelled, or Timed Out if a DEADLINE passed
+func (r *Runner) RunStatement(ctx context.Context, stmt parser.Statement) error {
+	if _, isTask := stmt.(*parser.Task); !isTask {
+		if err := context.Cause(ctx); err != nil {
+			r.Logger.AddLog(logger.NewLog(fmt.Sprintf("Skipping %s: %v", describeStatement(stmt), err)))
+			r.skipTasks(stmt, err)
+			return err
+		}
+	}
+
 	switch s := stmt.(type) {
 	case *parser.Task:
-		err := RunTask(s, globalData, globalPermissions, l)
+		path := r.path
+		if path == "" {
+			path = s.TaskName
+		}
+		release := r.acquireWorker(ctx, s)
+		result, err := runTask(ctx, s, r.GlobalData, r.Permissions, r.Executor, r.Logger)
+		release()
+		result.Path = path
+		r.results.add(result)
 		if err != nil {
-			*errors = append(*errors, err.Error())
+			return err
 		}
+		if s.Compensation != nil {
+			r.compensations.push(compensation{task: s.Compensation, compensates: s.TaskName, path: path, globalData: r.GlobalData, permissions: r.Permissions})
+		}
+		return nil
 	case *parser.RunSeqBlock:
-		RunSeqBlock(s, globalData, globalPermissions, l, errors)
+		return r.RunSeqBlock(ctx, s)
 	case *parser.RunConBlock:
-		RunConBlock(s, globalData, globalPermissions, l, errors)
+		return r.RunConBlock(ctx, s)
+	case *parser.RunAnyBlock:
+		return r.RunAnyBlock(ctx, s)
+	case *parser.AutoBlock:
+		return r.RunAutoBlock(ctx, s)
+	case *parser.IfBlock:
+		return r.RunIfBlock(ctx, s)
+	case *parser.ForEachBlock:
+		return r.RunForEachBlock(ctx, s)
+	case *parser.LoopBlock:
+		return r.RunLoopBlock(ctx, s)
+	case *parser.TryBlock:
+		return r.RunTryBlock(ctx, s)
 	default:
-		errMsg := "Unknown statement type"
-		fmt.Println(errMsg)
-		*errors = append(*errors, errMsg)
+		return fmt.Errorf("unexpected statement %T", s)
 	}
 }
 
-// RunSeqBlock runs the tasks sequentially
-func RunSeqBlock(seqBlock *parser.RunSeqBlock, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission, l *logger.Logger, errors *[]string) {
-	for _, stmt := range seqBlock.Statements {
-		RunStatement(stmt, globalData, globalPermissions, l, errors)
+// skipTasks records every task in a statement that is skipped because ctx ended with cause, at the path it
+// would have run at, as the executor does for tasks stopped before they start. Loop bodies are recorded
+// once, without an iteration, and both branches of an IF and the CATCH and FINALLY bodies of a TRY are
+// included, since none of them will run.
+func (r *Runner) skipTasks(stmt parser.Statement, cause error) {
+	switch s := stmt.(type) {
+	case *parser.Task:
+		path := r.path
+		if path == "" {
+			path = s.TaskName
+		}
+		status, outcome := executor.StoppedOutcome(cause)
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("Task %s %s before it started: %v", s.TaskName, outcome, cause)))
+		now := time.Now()
+		r.results.add(TaskResult{
+			Path:     path,
+			Name:     s.TaskName,
+			Agent:    s.AgentName,
+			Status:   status,
+			Err:      &TaskError{TaskName: s.TaskName, AgentName: s.AgentName, Err: fmt.Errorf("task %s: %w", outcome, cause)},
+			Started:  now,
+			Finished: now,
+		})
+	case *parser.RunSeqBlock:
+		r.skipSequence(s.Statements, cause)
+	case *parser.RunConBlock:
+		for i, child := range s.Statements {
+			r.at(s.Keys[i]).skipTasks(child, cause)
+		}
+	case *parser.AutoBlock:
+		for i, child := range s.Statements {
+			r.at(s.Keys[i]).skipTasks(child, cause)
+		}
+	case *parser.RunAnyBlock:
+		r.skipSequence(s.Statements, cause)
+	case *parser.IfBlock:
+		r.skipSequence(s.Then, cause)
+		r.at("ELSE").skipSequence(s.Else, cause)
+	case *parser.ForEachBlock:
+		r.skipSequence(s.Body, cause)
+	case *parser.LoopBlock:
+		r.skipSequence(s.Body, cause)
+	case *parser.TryBlock:
+		r.skipSequence(s.Body, cause)
+		r.at("CATCH").skipSequence(s.Catch, cause)
+		r.at("FINALLY").skipSequence(s.Finally, cause)
 	}
 }
 
-// RunConBlock runs the tasks concurrently
-func RunConBlock(conBlock *parser.RunConBlock, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission, l *logger.Logger, errors *[]string) {
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+// skipSequence records the tasks of each statement in a list as skipped.
+func (r *Runner) skipSequence(statements []parser.Statement, cause error) {
+	for i, stmt := range statements {
+		r.at(pathSegment(stmt, i)).skipTasks(stmt, cause)
+	}
+}
+
+// describeStatement names a statement and where it starts, for logs
+func describeStatement(stmt parser.Statement) string {
+	name := statementKeyword(stmt)
+	if t, isTask := stmt.(*parser.Task); isTask {
+		name += " " + t.TaskName
+	}
+	return fmt.Sprintf("%s at %s", name, stmt.GetSpan().Start)
+}
+
+// statementKeyword returns the keyword a statement starts with
+func statementKeyword(stmt parser.Statement) string {
+	switch s := stmt.(type) {
+	case *parser.Task:
+		return "TASK"
+	case *parser.RunSeqBlock:
+		return "RUNSEQ"
+	case *parser.RunConBlock:
+		return "RUNCON"
+	case *parser.RunAnyBlock:
+		return s.Keyword()
+	case *parser.AutoBlock:
+		return "AUTO"
+	case *parser.IfBlock:
+		return "IF"
+	case *parser.ForEachBlock:
+		return "FOREACH"
+	case *parser.LoopBlock:
+		return s.Keyword()
+	case *parser.TryBlock:
+		return "TRY"
+	default:
+		return "statement"
+	}
+}
 
-	for _, stmt := range conBlock.Statements {
+// runSequence runs the statements in order. If the runner fails fast, it stops at the first failure and
+// skips the rest; if ctx is done by then, the tasks skipped are recorded as cancelled or timed out
+func (r *Runner) runSequence(ctx context.Context, statements []parser.Statement) error {
+	var errs []error
+	for i, stmt := range statements {
+		err := r.at(pathSegment(stmt, i)).RunStatement(ctx, stmt)
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		if r.FailFast {
+			if remaining := len(statements) - i - 1; remaining > 0 && !errors.Is(err, ErrStopped) {
+				r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s failed; skipping the rest of the sequence (%d statements)", describeStatement(stmt), remaining)))
+			}
+			if cause := context.Cause(ctx); cause != nil {
+				for j := i + 1; j < len(statements); j++ {
+					r.at(pathSegment(statements[j], j)).skipTasks(statements[j], cause)
+				}
+			}
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runBranches runs n branches concurrently and waits for all of them. If the runner fails fast, the first
+// failure cancels the branches still running, and their being stopped is not reported as a failure of its own
+func (r *Runner) runBranches(ctx context.Context, n int, run func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// Errors are stored by index so they are reported in declaration order
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
 		wg.Add(1)
-		go func(s interface{}) {
+		go func(i int) {
 			defer wg.Done()
-			localErrors := []string{}
-			RunStatement(s, globalData, globalPermissions, l, &localErrors)
-			if len(localErrors) > 0 {
-				mu.Lock()
-				*errors = append(*errors, localErrors...)
-				mu.Unlock()
+			errs[i] = run(ctx, i)
+			if errs[i] != nil && r.FailFast {
+				cancel(ErrStopped)
 			}
-		}(stmt)
+		}(i)
 	}
 	wg.Wait()
+
+	var failures []error
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, ErrStopped) {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) == 0 {
+		// Nothing failed here, so any error comes from a failure outside the block
+		return errors.Join(errs...)
+	}
+	return errors.Join(failures...)
 }
 
+// runWithDeadline runs the given function, stopping it once the deadline passes if there is one. A run
+// that fails because its deadline passed reports the deadline, unless its error already does
+func (r *Runner) runWithDeadline(ctx context.Context, scope string, deadline time.Duration, run func(ctx context.Context) error) error {
+	if deadline <= 0 {
+		return run(ctx)
+	}
+	exceeded := &DeadlineError{Scope: scope, Deadline: deadline}
+	ctx, cancel := context.WithTimeoutCause(ctx, deadline, exceeded)
+	defer cancel()
 
-// RunTask executes a task and handles any errors
-func RunTask(t *parser.Task, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission, l *logger.Logger) error {
-	// Simulate task execution time
-	time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
+	err := run(ctx)
+	if err != nil && context.Cause(ctx) == exceeded {
+		r.Logger.AddLog(logger.NewLog("Error: " + exceeded.Error()))
+		if !errors.Is(err, exceeded) {
+			return errors.Join(exceeded, err)
+		}
+	}
+	return err
+}
 
-	// Execute the task using the executor package
-	err := executor.ExecuteTask(t.AgentName, t, globalData, globalPermissions, l)
-	if err != nil {
+// RunSeqBlock runs the tasks sequentially, within the block's DEADLINE if it has one
+func (r *Runner) RunSeqBlock(ctx context.Context, seqBlock *parser.RunSeqBlock) error {
+	block := r.withPolicy(seqBlock.OnError)
+	return block.runWithDeadline(ctx, describeStatement(seqBlock), seqBlock.Deadline, func(ctx context.Context) error {
+		return block.runSequence(ctx, seqBlock.Statements)
+	})
+}
+
+// RunConBlock runs the tasks concurrently, at most MAX at a time and within the block's DEADLINE if it has
+// them. A task with AFTER starts as soon as the statements it names have finished, and is skipped if any of
+// them failed
+func (r *Runner) RunConBlock(ctx context.Context, conBlock *parser.RunConBlock) error {
+	description := describeStatement(conBlock)
+	block := r.withPolicy(conBlock.OnError)
+	return block.runWithDeadline(ctx, description, conBlock.Deadline, func(ctx context.Context) error {
+		return block.runGraph(ctx, description, analysis.DependencyGraph(conBlock), conBlock.Keys, conBlock.Statements, conBlock.Max)
+	})
+}
+
+// RunAutoBlock runs the statements of an AUTO block concurrently, each as soon as the statements it depends
+// on have finished. The dependencies are inferred from the data each statement reads and writes, and logged
+// before anything runs
+func (r *Runner) RunAutoBlock(ctx context.Context, autoBlock *parser.AutoBlock) error {
+	description := describeStatement(autoBlock)
+	graph := analysis.InferGraph(autoBlock)
+	r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: inferred dependencies:\n%s", description, graph)))
+
+	block := r.withPolicy(autoBlock.OnError)
+	return block.runWithDeadline(ctx, description, autoBlock.Deadline, func(ctx context.Context) error {
+		return block.runGraph(ctx, description, graph, autoBlock.Keys, autoBlock.Statements, 0)
+	})
+}
+
+// runGraph runs keyed statements concurrently, each once the statements it depends on in the graph have
+// finished, and no more than max at a time if max is positive. Statements take a slot only once they are
+// ready, so those waiting on dependencies never hold one up. Nothing runs if the graph has a cycle
+func (r *Runner) runGraph(ctx context.Context, description string, graph *analysis.Graph, keys []string, statements []parser.Statement, max int) error {
+	if err := graph.Check(); err != nil {
+		err = fmt.Errorf("%s: %w", description, err)
+		r.Logger.AddLog(logger.NewLog("Error: " + err.Error()))
 		return err
 	}
 
-	// Optionally print task information
-	PrintTask(t)
+	// Each statement closes its channel when it finishes, after recording whether it failed
+	index := make(map[string]int, len(keys))
+	finished := make([]chan struct{}, len(statements))
+	failed := make([]bool, len(statements))
+	for i, key := range keys {
+		index[key] = i
+		finished[i] = make(chan struct{})
+	}
+
+	slots := newSemaphore(max)
+	return r.runBranches(ctx, len(statements), func(ctx context.Context, i int) error {
+		err := r.waitForDependencies(ctx, statements[i], graph.Dependencies(keys[i]), index, finished, failed)
+		if err == nil {
+			release := r.acquireSlot(ctx, slots, max, statements[i], description)
+			err = r.at(keys[i]).RunStatement(ctx, statements[i])
+			release()
+		}
+		failed[i] = err != nil
+		close(finished[i])
+		return err
+	})
+}
+
+// waitForDependencies waits for the dependencies of a statement to finish. It returns an error if one of
+// them failed, so the statement is skipped, and returns early if ctx is cancelled, leaving RunStatement to
+// skip it
+func (r *Runner) waitForDependencies(ctx context.Context, stmt parser.Statement, dependencies []string, index map[string]int, finished []chan struct{}, failed []bool) error {
+	for _, dependency := range dependencies {
+		select {
+		case <-finished[index[dependency]]:
+		case <-ctx.Done():
+			return nil
+		}
+		if failed[index[dependency]] {
+			err := fmt.Errorf("%s skipped: %w", describeStatement(stmt), &DependencyError{Dependency: dependency})
+			r.Logger.AddLog(logger.NewLog(fmt.Sprintf("Skipping %s: it runs AFTER %s, which failed", describeStatement(stmt), dependency)))
+			return err
+		}
+	}
+	if len(dependencies) > 0 {
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("Starting %s: %s finished", describeStatement(stmt), strings.Join(dependencies, ", "))))
+	}
 	return nil
 }
 
-// PrintTask prints the task information to the console
-func PrintTask(t *parser.Task) {
-	fmt.Printf("Task: %s, Agent: %s, Parameters: %v\n", t.TaskName, t.AgentName, t.Parameters)
+// RunIfBlock evaluates the condition and runs the branch it selects, logging which branch was taken and why
+func (r *Runner) RunIfBlock(ctx context.Context, ifBlock *parser.IfBlock) error {
+	description := fmt.Sprintf("IF %s at %s", ifBlock.Condition, ifBlock.Span.Start)
+
+	result, reason, err := EvaluateCondition(ifBlock.Condition, r.GlobalData)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", description, err)
+		r.Logger.AddLog(logger.NewLog("Error evaluating " + err.Error()))
+		return err
+	}
+
+	branch, runner := ifBlock.Then, r
+	switch {
+	case result:
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s is true (%s); running THEN branch", description, reason)))
+	case ifBlock.Else != nil:
+		branch, runner = ifBlock.Else, r.at("ELSE")
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s is false (%s); running ELSE branch", description, reason)))
+	default:
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s is false (%s); no ELSE branch, skipping", description, reason)))
+		return nil
+	}
+
+	return runner.runSequence(ctx, branch)
+}
+
+// RunForEachBlock runs the body once per item of the list, in order or concurrently, and collects each
+// iteration's result into the output list if the loop has a COLLECT clause
+func (r *Runner) RunForEachBlock(ctx context.Context, forEach *parser.ForEachBlock) error {
+	description := fmt.Sprintf("FOREACH %s IN %s at %s", forEach.Item, forEach.List, forEach.Span.Start)
+
+	items, err := ListItems(r.GlobalData, forEach.List)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", description, err)
+		r.Logger.AddLog(logger.NewLog("Error starting " + err.Error()))
+		return err
+	}
+	mode := "sequentially"
+	if forEach.Concurrent {
+		mode = "concurrently"
+	}
+	r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: running %d iterations %s", description, len(items), mode)))
+
+	// Results are stored by index so concurrent iterations keep the order of the list
+	results := make([]string, len(items))
+	runIteration := func(ctx context.Context, i int) error {
+		data, permissions := iterationScope(forEach, items[i], r.GlobalData, r.Permissions)
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: iteration %d with %s = %q", description, i, forEach.Item, items[i])))
+		err := r.withScope(data, permissions).at(fmt.Sprintf("#%d", i)).runSequence(ctx, forEach.Body)
+		if forEach.CollectVar != "" {
+			collected := data[forEach.CollectVar]
+			collected.Mu.Lock()
+			results[i] = collected.InitialValue
+			collected.Mu.Unlock()
+		}
+		return err
+	}
+
+	var iterationErr error
+	if forEach.Concurrent {
+		iterationErr = r.runBranches(ctx, len(items), runIteration)
+	} else {
+		var errs []error
+		for i := range items {
+			if err := runIteration(ctx, i); err != nil {
+				errs = append(errs, err)
+				if r.FailFast {
+					break
+				}
+			}
+		}
+		iterationErr = errors.Join(errs...)
+	}
+
+	if forEach.CollectVar == "" {
+		return iterationErr
+	}
+	collected, err := writeCollected(r.GlobalData, forEach.CollectInto, results)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", description, err)
+		r.Logger.AddLog(logger.NewLog("Error collecting results of " + err.Error()))
+		return errors.Join(iterationErr, err)
+	}
+	r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: collected %s into %s: %s", description, forEach.CollectVar, forEach.CollectInto, collected)))
+	return iterationErr
+}
+
+// RunLoopBlock runs the body of a WHILE or UNTIL loop until its condition says to stop, pausing between
+// iterations. The loop fails if it is still running after MAX iterations or if an iteration has errors
+func (r *Runner) RunLoopBlock(ctx context.Context, loop *parser.LoopBlock) error {
+	description := fmt.Sprintf("%s %s at %s", loop.Keyword(), loop.Condition, loop.Span.Start)
+
+	for i := 0; ; i++ {
+		result, reason, err := EvaluateCondition(loop.Condition, r.GlobalData)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", description, err)
+			r.Logger.AddLog(logger.NewLog("Error evaluating " + err.Error()))
+			return err
+		}
+
+		// UNTIL stops once the condition holds, WHILE once it no longer does
+		if result == loop.Until {
+			r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s is %v (%s); stopping after %d iterations", description, result, reason, i)))
+			return nil
+		}
+		if i == loop.Max {
+			err := fmt.Errorf("%s is still %v (%s) after MAX %d iterations", description, result, reason, loop.Max)
+			r.Logger.AddLog(logger.NewLog("Error: " + err.Error()))
+			return err
+		}
+
+		if i > 0 && loop.Every > 0 {
+			timer := time.NewTimer(loop.Every)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: stopping before iteration %d: %v", description, i, context.Cause(ctx))))
+				return context.Cause(ctx)
+			}
+		}
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: iteration %d of at most %d (%s)", description, i, loop.Max, reason)))
+
+		if err := r.at(fmt.Sprintf("#%d", i)).runSequence(ctx, loop.Body); err != nil {
+			r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: stopping after errors in iteration %d", description, i)))
+			return err
+		}
+	}
+}
+
+// RunTryBlock runs the body of a TRY block, stopping at its first failure and compensating the tasks it
+// completed. A failure is handed to the CATCH body, which can read the failing task's name and the error
+// message; the block fails only if there is no CATCH or the CATCH body fails too. FINALLY runs last in every
+// case, even if the run is being stopped
+func (r *Runner) RunTryBlock(ctx context.Context, tryBlock *parser.TryBlock) error {
+	description := fmt.Sprintf("TRY at %s", tryBlock.Span.Start)
+
+	// The body keeps its own compensations: they are undone if it fails, and handed to the enclosing
+	// block if it succeeds
+	body := *r
+	body.FailFast = true
+	body.compensations = &compensationStack{}
+	err := body.runSequence(ctx, tryBlock.Body)
+	if err == nil {
+		r.compensations.push(body.compensations.drain()...)
+	} else if compensateErr := body.Compensate(ctx); compensateErr != nil {
+		err = errors.Join(err, compensateErr)
+	}
+
+	// A body stopped from outside is not handled here
+	if err != nil && tryBlock.Catch != nil && ctx.Err() == nil {
+		taskName, message := describeFailure(err)
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s failed in task '%s': %s; running CATCH", description, taskName, message)))
+		data, permissions := catchScope(tryBlock, taskName, message, r.GlobalData, r.Permissions)
+		err = r.withScope(data, permissions).at("CATCH").runSequence(ctx, tryBlock.Catch)
+		if err != nil {
+			r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: CATCH failed: %v", description, err)))
+		}
+	}
+
+	if tryBlock.Finally != nil {
+		r.Logger.AddLog(logger.NewLog(fmt.Sprintf("%s: running FINALLY", description)))
+		if finallyErr := r.at("FINALLY").runSequence(context.WithoutCancel(ctx), tryBlock.Finally); finallyErr != nil {
+			err = errors.Join(err, finallyErr)
+		}
+	}
+	return err
+}
+
+// RunTask executes a task and handles any errors
+func RunTask(ctx context.Context, t *parser.Task, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission, e *executor.Executor, l *logger.Logger) error {
+	_, err := runTask(ctx, t, globalData, globalPermissions, e, l)
+	return err
+}
+
+// runTask executes a task like RunTask and returns what happened to it, leaving the path to the caller
+func runTask(ctx context.Context, t *parser.Task, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission, e *executor.Executor, l *logger.Logger) (TaskResult, error) {
+	result := TaskResult{Name: t.TaskName, Agent: t.AgentName, Started: time.Now()}
+
+	// Execute the task using the executor package
+	tracked, err := e.Execute(ctx, t.AgentName, t, globalData, globalPermissions, l)
+	result.Finished = time.Now()
+	result.Status = tracked.Status
+	result.Owner = tracked.Owner
+	result.Attempts = tracked.Attempts
+	result.Results = tracked.Result
+	if err != nil {
+		result.Err = &TaskError{TaskName: t.TaskName, AgentName: t.AgentName, Err: err}
+		return result, result.Err
+	}
+	if output, ok := t.Output(); ok {
+		if d, found := globalData[output]; found {
+			d.Mu.Lock()
+			result.Output = d.InitialValue
+			d.Mu.Unlock()
+		}
+	}
+	return result, nil
 }
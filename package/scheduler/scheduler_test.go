@@ -1,7 +1,10 @@
 package scheduler_test
 
 import (
+	"context"
 	"testing"
+	"trace/package/agent"
+	"trace/package/executor"
 	"trace/package/logger"
 	"trace/package/parser"
 	"trace/package/scheduler"
@@ -57,10 +60,10 @@ END
 	}
 
     l := logger.NewLogger()
-	success := scheduler.RunParentRequest(parentRequest, l)
+	success := scheduler.RunParentRequest(context.Background(), parentRequest, executor.NewMockExecutor(agent.NewMockRegistry()), l).Succeeded()
     l.PrintAllLogs()
 
 	if !success {
-		t.Fatalf("RunParentRequest returned false")
+		t.Fatalf("RunParentRequest failed")
 	}
 }
@@ -0,0 +1,26 @@
+package scheduler
+
+import (
+	"errors"
+	"trace/package/parser"
+)
+
+// describeFailure returns the name of the task behind a failure, or empty if it was not a task's, and the
+// error message to show a CATCH body.
+func describeFailure(err error) (string, string) {
+	var taskErr *TaskError
+	if errors.As(err, &taskErr) {
+		return taskErr.TaskName, taskErr.Err.Error()
+	}
+	return "", err.Error()
+}
+
+// catchScope returns the global data and permissions seen by the CATCH body of a TRY block: every agent
+// in the body may READ the name of the failed task and the error message.
+func catchScope(tryBlock *parser.TryBlock, taskName string, message string, globalData map[string]*parser.Data, globalPermissions map[string]*parser.Permission) (map[string]*parser.Data, map[string]*parser.Permission) {
+	locals := []scopedData{
+		{Data: &parser.Data{DataName: parser.CatchTaskVar, DataType: "String", InitialValue: taskName}, Access: []string{"READ"}},
+		{Data: &parser.Data{DataName: parser.CatchMessageVar, DataType: "String", InitialValue: message}, Access: []string{"READ"}},
+	}
+	return extendScope(tryBlock.Catch, locals, globalData, globalPermissions)
+}
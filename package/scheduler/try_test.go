@@ -0,0 +1,166 @@
+package scheduler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"trace/package/executor"
+	"trace/package/logger"
+	"trace/package/scheduler"
+)
+
+// recordingAgents is a set of in-process agents that record the payloads they are sent, in call order.
+type recordingAgents struct {
+	mu       sync.Mutex
+	calls    []string
+	payloads map[string]map[string]string
+}
+
+// called returns the agents called so far. Calls abandoned by a cancelled context may still land later.
+func (r *recordingAgents) called() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+// newRecordingExecutor returns an executor whose agents echo a task and message parameter back to the recorder.
+// A "slow" message takes 100ms, a "late" message 5ms and a "fail" message fails after 20ms. Agents in the script but not in
+// agentNames are unregistered, so their tasks fail at once.
+func newRecordingExecutor(t *testing.T, agentNames ...string) (*executor.Executor, *recordingAgents) {
+	t.Helper()
+	recorder := &recordingAgents{payloads: make(map[string]map[string]string)}
+	e := newInProcessExecutor(t, map[string]interface{}{"task": "[[task]]", "message": "[[message]]"}, func(agentName string, jsonPayload string) (string, error) {
+		payload := map[string]string{}
+		if err := json.Unmarshal([]byte(jsonPayload), &payload); err != nil {
+			t.Errorf("Invalid payload for %s: %v", agentName, err)
+		}
+		switch payload["message"] {
+		case "slow":
+			time.Sleep(100 * time.Millisecond)
+		case "late":
+			time.Sleep(5 * time.Millisecond)
+		case "fail":
+			time.Sleep(20 * time.Millisecond)
+		}
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		recorder.calls = append(recorder.calls, agentName)
+		recorder.payloads[agentName] = payload
+		if payload["message"] == "fail" {
+			return "", errors.New(agentName + " is overloaded")
+		}
+		return agentName + " done", nil
+	}, agentNames...)
+	return e, recorder
+}
+
+// TestRunTryBlock_Catch tests that a failure deep inside the TRY body stops it and reaches the CATCH body.
+func TestRunTryBlock_Catch(t *testing.T) {
+	pr := parseScript(t, `START
+TRY {
+    RUNSEQ {
+        TASK ScheduleFlight AGENT Flights PARAMETERS (task="flight", message="") ;
+        RUNCON {
+            TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") ;
+        }
+        TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="") ;
+    }
+} CATCH {
+    TASK NotifyUser AGENT Notifier PARAMETERS (task=errorTask, message=errorMessage) ;
+} FINALLY {
+    TASK Cleanup AGENT Janitor PARAMETERS (task="cleanup", message="") ;
+}
+END`)
+	e, recorder := newRecordingExecutor(t, "Flights", "Hotels", "Notifier", "Janitor")
+
+	l := logger.NewLogger()
+	if !scheduler.RunParentRequest(context.Background(), pr, e, l).Succeeded() {
+		t.Fatal("Expected the handled failure to let the run succeed")
+	}
+
+	if expected := []string{"Flights", "Notifier", "Janitor"}; !reflect.DeepEqual(recorder.calls, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.calls)
+	}
+	notified := recorder.payloads["Notifier"]
+	if notified["task"] != "ScheduleRide" {
+		t.Errorf("Expected CATCH to see the failed task ScheduleRide, got %q", notified["task"])
+	}
+	if !strings.Contains(notified["message"], "agent 'Rides' not found") {
+		t.Errorf("Expected CATCH to see the error message, got %q", notified["message"])
+	}
+
+	all := l.Text()
+	for _, expected := range []string{
+		"TRY at 2:1 failed in task 'ScheduleRide': agent 'Rides' not found; running CATCH",
+		"TRY at 2:1: running FINALLY",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected log %q, got:\n%s", expected, all)
+		}
+	}
+}
+
+// TestRunTryBlock_Uncaught tests that a failure without CATCH still runs FINALLY and fails the run.
+func TestRunTryBlock_Uncaught(t *testing.T) {
+	pr := parseScript(t, `START
+TRY {
+    TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") ;
+} FINALLY {
+    TASK Cleanup AGENT Janitor PARAMETERS (task="cleanup", message="") ;
+}
+TASK Report AGENT Reporter PARAMETERS (task="report", message="") ;
+END`)
+	e, recorder := newRecordingExecutor(t, "Janitor", "Reporter")
+
+	if scheduler.RunParentRequest(context.Background(), pr, e, logger.NewLogger()).Succeeded() {
+		t.Fatal("Expected the unhandled failure to fail the run")
+	}
+	if expected := []string{"Janitor", "Reporter"}; !reflect.DeepEqual(recorder.calls, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.calls)
+	}
+}
+
+// TestRunTryBlock_CatchFails tests that a failing CATCH body fails the TRY block.
+func TestRunTryBlock_CatchFails(t *testing.T) {
+	pr := parseScript(t, `START
+TRY {
+    TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") ;
+} CATCH {
+    TASK NotifyUser AGENT Pager PARAMETERS (task=errorTask, message=errorMessage) ;
+}
+END`)
+	e, _ := newRecordingExecutor(t)
+
+	r := scheduler.NewRunner(pr, e, logger.NewLogger())
+	err := r.RunStatement(context.Background(), pr.Statements[0])
+	if err == nil {
+		t.Fatal("Expected the failing CATCH to fail the TRY block")
+	}
+	var taskErr *scheduler.TaskError
+	if !errors.As(err, &taskErr) || taskErr.TaskName != "NotifyUser" {
+		t.Errorf("Expected the CATCH task's failure, got %v", err)
+	}
+}
+
+// TestRunSeqBlock_ContinuesOutsideTry tests that sequences outside TRY keep going after a failure.
+func TestRunSeqBlock_ContinuesOutsideTry(t *testing.T) {
+	pr := parseScript(t, `START
+RUNSEQ {
+    TASK ScheduleRide AGENT Rides PARAMETERS (task="ride", message="") ;
+    TASK BookHotel AGENT Hotels PARAMETERS (task="hotel", message="") ;
+}
+END`)
+	e, recorder := newRecordingExecutor(t, "Hotels")
+
+	if scheduler.RunParentRequest(context.Background(), pr, e, logger.NewLogger()).Succeeded() {
+		t.Fatal("Expected the failure to fail the run")
+	}
+	if expected := []string{"Hotels"}; !reflect.DeepEqual(recorder.calls, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, recorder.calls)
+	}
+}
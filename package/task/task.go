@@ -15,6 +15,9 @@ const (
 	Claimed
 	InProgress
 	Finished
+	Failed
+	Cancelled
+	TimedOut
 )
 
 // Task represents a unit of work.
@@ -25,6 +28,7 @@ type Task struct {
 	Status      Status
 	Parameters  map[string]interface{}
 	Result      []string
+	Attempts    int // Calls made to the agent, counting retries
 	mu sync.Mutex
 }
 
@@ -73,6 +77,14 @@ func (t *Task) UpdateParameters(newParameters map[string]interface{}) {
 	}
 }
 
+// RecordAttempt counts one more call to the task's agent.
+func (t *Task) RecordAttempt() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Attempts++
+}
+
 // UpdateResult appends an item to the task's results.
 func (t *Task) UpdateResult(item string) {
 	t.mu.Lock()
@@ -81,24 +93,32 @@ func (t *Task) UpdateResult(item string) {
 	t.Result = append(t.Result, item)
 }
 
-// DisplayTask prints the task's details.
-func (t *Task) GetInfoString() string {
-	statusNum := t.Status
-	var status string
-	switch statusNum {
-	case 0:
-		status = "Pending"
-	case 1:
-		status = "Claimed"
-	case 2:
-		status = "In Progress"
-	case 3:
-		status = "Finished"
+// String returns the status as shown in task details, such as "In Progress".
+func (s Status) String() string {
+	switch s {
+	case Pending:
+		return "Pending"
+	case Claimed:
+		return "Claimed"
+	case InProgress:
+		return "In Progress"
+	case Finished:
+		return "Finished"
+	case Failed:
+		return "Failed"
+	case Cancelled:
+		return "Cancelled"
+	case TimedOut:
+		return "Timed Out"
 	default:
-		status = "Unknown"
+		return "Unknown"
 	}
+}
+
+// DisplayTask prints the task's details.
+func (t *Task) GetInfoString() string {
     return fmt.Sprintf(
         "Task ID: %d\nDescription: %s\nStatus: %s\nOwner: %v\nParameters: %v\nResults: %v\n",
-        t.ID, t.Description, status, t.Owner, t.Parameters, t.Result,
+        t.ID, t.Description, t.Status, t.Owner, t.Parameters, t.Result,
     )
 }
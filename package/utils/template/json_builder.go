@@ -3,9 +3,15 @@ package template
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 )
 
+// placeholderPattern matches a well-formed [[placeholder]] occupying a whole string value.
+var placeholderPattern = regexp.MustCompile(`^\[\[([A-Za-z_][A-Za-z0-9_]*)\]\]$`)
+
 // LoadJSON builds the final JSON string with proper data types. It ensures that all placeholders are filled; otherwise, it returns an error.
 func LoadJSON(jsonTemplate map[string]interface{}, taskParameters map[string]interface{}, globalData map[string]interface{}) (string, error) {
 	templateCopy := deepCopyMap(jsonTemplate)
@@ -22,19 +28,52 @@ func LoadJSON(jsonTemplate map[string]interface{}, taskParameters map[string]int
 	return string(finalJson), nil
 }
 
-//Function that loads correct parameter values based off global data and permissions
-func LoadTaskParameters(params map[string]interface{}, globalData map[string]interface{}) map[string]interface{} {
-	for parameterKey, parameterValue := range params {
-		strParamValue, ok := parameterValue.(string)
-		if !ok {
-			continue
-		}
+// Placeholders returns the sorted, de-duplicated names of every [[placeholder]] in the template.
+// It returns an error if a value contains "[[" or "]]" without being a single well-formed placeholder.
+func Placeholders(jsonTemplate map[string]interface{}) ([]string, error) {
+	found := make(map[string]bool)
+	if err := collectPlaceholders(jsonTemplate, "", found); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
 
-		if globalValue, found := globalData[strParamValue]; found {
-			params[parameterKey] = globalValue
+// Helper function to recursively collect placeholder names, reporting malformed ones by their path in the template.
+func collectPlaceholders(value interface{}, path string, found map[string]bool) error {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "[[") && !strings.Contains(v, "]]") {
+			return nil
+		}
+		match := placeholderPattern.FindStringSubmatch(v)
+		if match == nil {
+			return fmt.Errorf("malformed placeholder %q at '%s'", v, path)
+		}
+		found[match[1]] = true
+	case map[string]interface{}:
+		for key, item := range v {
+			itemPath := key
+			if path != "" {
+				itemPath = path + "." + key
+			}
+			if err := collectPlaceholders(item, itemPath, found); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if err := collectPlaceholders(item, fmt.Sprintf("%s[%d]", path, i), found); err != nil {
+				return err
+			}
 		}
 	}
-	return params
+	return nil
 }
 
 // Helper function to recursively replace placeholders. Returns false if any placeholders remain unfilled.
@@ -128,3 +128,57 @@ func TestLoadJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestPlaceholders(t *testing.T) {
+	tests := []struct {
+		name         string
+		jsonTemplate map[string]interface{}
+		expected     []string
+		expectError  bool
+	}{
+		{
+			name: "Nested and repeated placeholders",
+			jsonTemplate: map[string]interface{}{
+				"action": "search",
+				"params": map[string]interface{}{
+					"origin": "[[origin]]",
+					"dates":  []interface{}{"[[date]]", "[[date]]"},
+				},
+			},
+			expected: []string{"date", "origin"},
+		},
+		{
+			name:         "No placeholders",
+			jsonTemplate: map[string]interface{}{"action": "ping"},
+			expected:     []string{},
+		},
+		{
+			name:         "Unclosed placeholder",
+			jsonTemplate: map[string]interface{}{"params": map[string]interface{}{"origin": "[[origin"}},
+			expectError:  true,
+		},
+		{
+			name:         "Placeholder embedded in text",
+			jsonTemplate: map[string]interface{}{"query": "from [[origin]]"},
+			expectError:  true,
+		},
+		{
+			name:         "Invalid placeholder name",
+			jsonTemplate: map[string]interface{}{"origin": "[[origin city]]"},
+			expectError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := template.Placeholders(tt.jsonTemplate)
+
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error: %v, got: %v", tt.expectError, err)
+			}
+			if !tt.expectError && !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("Expected: %v, got: %v", tt.expected, names)
+			}
+		})
+	}
+}
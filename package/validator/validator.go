@@ -0,0 +1,305 @@
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"trace/package/agent"
+	"trace/package/analysis"
+	"trace/package/parser"
+)
+
+// AccessKeywords are the permissions the executor enforces.
+var AccessKeywords = []string{"READ", "WRITE"}
+
+// Severity is how serious an issue is. A script with errors should not be run; warnings point at code that
+// runs but may not do what was meant.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+// String returns "error" or "warning".
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Issue is a semantic problem in a parsed script.
+type Issue struct {
+	Span     parser.Span
+	Message  string
+	Severity Severity
+}
+
+// Error returns the message prefixed with the line and column it starts at, and with "warning: " for warnings.
+func (i Issue) Error() string {
+	if i.Severity == SeverityWarning {
+		return fmt.Sprintf("line %d, column %d: warning: %s", i.Span.Start.Line, i.Span.Start.Column, i.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", i.Span.Start.Line, i.Span.Start.Column, i.Message)
+}
+
+// HasErrors reports whether any of the issues is an error rather than a warning.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Options change how a script is validated.
+type Options struct {
+	Strict bool // Report races between concurrent tasks as errors rather than warnings
+}
+
+// Validate checks a parsed script for problems that would otherwise only surface at runtime:
+// permissions on undeclared data, unknown access keywords, tasks naming agents that are not
+// registered, parameters or outputs the agent cannot read or write, conditions and loops on
+// undeclared data, loop variables that shadow global data, and dependencies that form a cycle or
+// AFTER clauses that are not directly inside a RUNCON or AUTO block. Tasks that may read and write the
+// same data at the same time are reported as warnings. A nil registry skips the agent check. Issues are
+// returned in source order.
+func Validate(pr *parser.ParentRequest, registry agent.AgentRegistry) []Issue {
+	return ValidateWithOptions(pr, registry, Options{})
+}
+
+// ValidateWithOptions checks a parsed script like Validate; in strict mode, races between concurrent tasks
+// are errors.
+func ValidateWithOptions(pr *parser.ParentRequest, registry agent.AgentRegistry, options Options) []Issue {
+	v := &validator{pr: pr, registry: registry}
+	v.checkPermissions()
+	v.checkDependencies()
+	v.checkRaces(options.Strict)
+	parser.WalkAll(scopeVisitor{v: v}, pr.Statements)
+
+	sort.Slice(v.issues, func(i, j int) bool {
+		a, b := v.issues[i].Span.Start, v.issues[j].Span.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return v.issues[i].Message < v.issues[j].Message
+	})
+	return v.issues
+}
+
+// validator collects issues for a single script.
+type validator struct {
+	pr       *parser.ParentRequest
+	registry agent.AgentRegistry
+	issues   []Issue
+}
+
+func (v *validator) report(span parser.Span, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{Span: span, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkPermissions reports grants on undeclared data and unknown access keywords.
+func (v *validator) checkPermissions() {
+	for agentName, perm := range v.pr.Permissions {
+		for dataName, permissions := range perm.DataPermissions {
+			span := perm.DataSpans[dataName]
+			if _, declared := v.pr.GlobalData[dataName]; !declared {
+				v.report(span, "agent '%s' is granted access to undeclared data '%s'", agentName, dataName)
+			}
+			for _, access := range permissions {
+				if !isAccessKeyword(access) {
+					v.report(span, "unknown access '%s' for agent '%s' on data '%s'; expected READ or WRITE", access, agentName, dataName)
+				}
+			}
+		}
+	}
+}
+
+// checkDependencies reports RUNCON and AUTO blocks whose dependencies form a cycle, and AFTER clauses on tasks
+// that are not directly inside such a block, where they would have no effect.
+func (v *validator) checkDependencies() {
+	keyed := make(map[*parser.Task]bool)
+	addTasks := func(statements []parser.Statement) {
+		for _, child := range statements {
+			if t, isTask := child.(*parser.Task); isTask {
+				keyed[t] = true
+			}
+		}
+	}
+	parser.InspectAll(v.pr.Statements, func(stmt parser.Statement) bool {
+		switch s := stmt.(type) {
+		case *parser.RunConBlock:
+			addTasks(s.Statements)
+			if err := analysis.DependencyGraph(s).Check(); err != nil {
+				v.report(s.Span, "RUNCON block has a %v", err)
+			}
+		case *parser.AutoBlock:
+			addTasks(s.Statements)
+			if err := analysis.InferGraph(s).Check(); err != nil {
+				v.report(s.Span, "AUTO block has a %v", err)
+			}
+		}
+		return true
+	})
+
+	parser.InspectAll(v.pr.Statements, func(stmt parser.Statement) bool {
+		if t, ok := stmt.(*parser.Task); ok && len(t.After) > 0 && !keyed[t] {
+			v.report(t.Span, "task '%s' runs AFTER other statements, which only has an effect directly inside a RUNCON or AUTO block", t.TaskName)
+		}
+		return true
+	})
+}
+
+// checkRaces reports tasks that may access the same data at the same time, with at least one writing it.
+func (v *validator) checkRaces(strict bool) {
+	severity := SeverityWarning
+	if strict {
+		severity = SeverityError
+	}
+	for _, race := range analysis.FindRaces(v.pr) {
+		v.issues = append(v.issues, Issue{Span: race.Span(), Message: race.String(), Severity: severity})
+	}
+}
+
+// scopeVisitor checks statements with the variables of the enclosing FOREACH loops and CATCH bodies in scope.
+type scopeVisitor struct {
+	v      *validator
+	locals map[string]bool // Block-scoped variables, mapped to whether agents may write them
+}
+
+func (s scopeVisitor) Visit(stmt parser.Statement) parser.Visitor {
+	switch n := stmt.(type) {
+	case *parser.Task:
+		s.v.checkTask(n, s.locals)
+	case *parser.IfBlock:
+		s.v.checkCondition(n.Condition, s.locals)
+	case *parser.LoopBlock:
+		s.v.checkCondition(n.Condition, s.locals)
+	case *parser.ForEachBlock:
+		s.v.checkForEach(n, s.locals)
+		locals := make(map[string]bool, len(s.locals)+2)
+		for name, writable := range s.locals {
+			locals[name] = writable
+		}
+		locals[n.Item] = false
+		if n.CollectVar != "" {
+			locals[n.CollectVar] = true
+		}
+		return scopeVisitor{v: s.v, locals: locals}
+	case *parser.TryBlock:
+		// Only the CATCH body sees the failed task and error message
+		locals := make(map[string]bool, len(s.locals)+2)
+		for name, writable := range s.locals {
+			locals[name] = writable
+		}
+		locals[parser.CatchTaskVar] = false
+		locals[parser.CatchMessageVar] = false
+		parser.WalkAll(s, n.Body)
+		parser.WalkAll(scopeVisitor{v: s.v, locals: locals}, n.Catch)
+		parser.WalkAll(s, n.Finally)
+		return nil
+	}
+	return s
+}
+
+// checkTask reports unregistered agents, references the agent cannot read and outputs it cannot write.
+func (v *validator) checkTask(t *parser.Task, locals map[string]bool) {
+	if v.registry != nil && v.registry.GetByName(t.AgentName) == nil {
+		v.report(t.Span, "task '%s' uses agent '%s', which is not registered", t.TaskName, t.AgentName)
+	}
+
+	for key, param := range t.Parameters {
+		if key == "OUTPUT" || !param.IsReference() {
+			continue
+		}
+		if _, local := locals[param.Value]; local {
+			continue
+		}
+		if _, declared := v.pr.GlobalData[param.Value]; !declared {
+			v.report(t.Span, "task '%s' parameter '%s' refers to undeclared data '%s'", t.TaskName, key, param.Value)
+		} else if !v.hasAccess(t.AgentName, param.Value, "READ") {
+			v.report(t.Span, "task '%s' parameter '%s' refers to '%s', but agent '%s' does not have READ permission for it", t.TaskName, key, param.Value, t.AgentName)
+		}
+	}
+
+	output, ok := t.Output()
+	if !ok {
+		return
+	}
+	if writable, local := locals[output]; local {
+		if !writable {
+			v.report(t.Span, "task '%s' writes its output to '%s', which is read-only in this scope", t.TaskName, output)
+		}
+		return
+	}
+	if _, declared := v.pr.GlobalData[output]; !declared {
+		v.report(t.Span, "task '%s' writes its output to undeclared data '%s'", t.TaskName, output)
+		return
+	}
+	if !v.hasAccess(t.AgentName, output, "WRITE") {
+		v.report(t.Span, "task '%s' writes its output to '%s', but agent '%s' does not have WRITE permission for it", t.TaskName, output, t.AgentName)
+	}
+}
+
+// checkCondition reports conditions that refer to undeclared data.
+func (v *validator) checkCondition(c *parser.Condition, locals map[string]bool) {
+	for _, operand := range []parser.Parameter{c.Left, c.Right} {
+		if _, local := locals[operand.Value]; !operand.IsReference() || local {
+			continue
+		}
+		if _, declared := v.pr.GlobalData[operand.Value]; !declared {
+			v.report(c.Span, "condition '%s' refers to undeclared data '%s'", c, operand.Value)
+		}
+	}
+}
+
+// checkForEach reports loops over undeclared lists, undeclared collect targets and loop variables that hide global data.
+func (v *validator) checkForEach(forEach *parser.ForEachBlock, locals map[string]bool) {
+	if !v.isDeclared(forEach.List, locals) {
+		v.report(forEach.Span, "FOREACH loops over undeclared data '%s'", forEach.List)
+	}
+	if forEach.CollectVar != "" && !v.isDeclared(forEach.CollectInto, locals) {
+		v.report(forEach.Span, "FOREACH collects into undeclared data '%s'", forEach.CollectInto)
+	}
+	for _, name := range []string{forEach.Item, forEach.CollectVar} {
+		if name != "" && v.isDeclared(name, locals) {
+			v.report(forEach.Span, "FOREACH variable '%s' shadows data with the same name", name)
+		}
+	}
+}
+
+// isDeclared reports whether the name is global data or a variable of an enclosing loop.
+func (v *validator) isDeclared(name string, locals map[string]bool) bool {
+	if _, local := locals[name]; local {
+		return true
+	}
+	_, declared := v.pr.GlobalData[name]
+	return declared
+}
+
+// hasAccess reports whether the agent has been granted the access on the data.
+func (v *validator) hasAccess(agentName string, dataName string, access string) bool {
+	perm, ok := v.pr.Permissions[agentName]
+	if !ok {
+		return false
+	}
+	for _, granted := range perm.DataPermissions[dataName] {
+		if granted == access {
+			return true
+		}
+	}
+	return false
+}
+
+func isAccessKeyword(access string) bool {
+	for _, keyword := range AccessKeywords {
+		if access == keyword {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package validator_test
+
+import (
+	"reflect"
+	"testing"
+	"trace/package/agent"
+	"trace/package/parser"
+	"trace/package/validator"
+)
+
+// parse parses a script and fails the test on syntax errors.
+func parse(t *testing.T, input string) *parser.ParentRequest {
+	t.Helper()
+	p := parser.NewParser(parser.NewLexer(input))
+	pr := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+	return pr
+}
+
+// TestValidate_Valid tests that a well-formed script has no issues.
+func TestValidate_Valid(t *testing.T) {
+	pr := parse(t, `START
+DATA origin TYPE String VALUE "Chicago" ;
+DATA flightInfo TYPE String ;
+PERM AGENT FlightGetter DATA origin ACCESS READ ;
+PERM AGENT FlightGetter DATA flightInfo ACCESS READ, WRITE ;
+RUNSEQ {
+    TASK ScheduleFlight AGENT FlightGetter PARAMETERS (origin=origin, OUTPUT=flightInfo) ;
+    TASK CheckWeather AGENT WeatherChecker PARAMETERS (location="Chicago") ;
+}
+END`)
+
+	if issues := validator.Validate(pr, agent.NewMockRegistry()); len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+}
+
+// TestValidate_Issues tests that every problem is reported with its position, in source order.
+func TestValidate_Issues(t *testing.T) {
+	pr := parse(t, `START
+DATA flightInfo TYPE String ;
+DATA hotelInfo TYPE String ;
+PERM AGENT FlightGetter DATA origin ACCESS READ ;
+PERM AGENT RoomBooker DATA hotelInfo ACCESS ADD ;
+RUNSEQ {
+    TASK ScheduleFlight AGENT FlightGetter PARAMETERS (OUTPUT=flightInfo) ;
+    RUNCON {
+        TASK BookHotel AGENT RoomBooker PARAMETERS (OUTPUT=hotelInfo) ;
+        TASK Rent AGENT CarRenter PARAMETERS (OUTPUT=carInfo) ;
+    }
+    TASK Report AGENT RoomBooker PARAMETERS (summary=flightInfo, note="flightInfo", nights=2, guests=guestCount) ;
+    IF forecast CONTAINS "sun" {
+    }
+}
+END`)
+
+	var got []string
+	for _, issue := range validator.Validate(pr, agent.NewMockRegistry()) {
+		got = append(got, issue.Error())
+	}
+
+	expected := []string{
+		"line 4, column 30: agent 'FlightGetter' is granted access to undeclared data 'origin'",
+		"line 5, column 28: unknown access 'ADD' for agent 'RoomBooker' on data 'hotelInfo'; expected READ or WRITE",
+		"line 7, column 5: task 'ScheduleFlight' writes its output to 'flightInfo', but agent 'FlightGetter' does not have WRITE permission for it",
+		"line 9, column 9: task 'BookHotel' writes its output to 'hotelInfo', but agent 'RoomBooker' does not have WRITE permission for it",
+		"line 10, column 9: task 'Rent' uses agent 'CarRenter', which is not registered",
+		"line 10, column 9: task 'Rent' writes its output to undeclared data 'carInfo'",
+		"line 12, column 5: task 'Report' parameter 'guests' refers to undeclared data 'guestCount'",
+		"line 12, column 5: task 'Report' parameter 'summary' refers to 'flightInfo', but agent 'RoomBooker' does not have READ permission for it",
+		"line 13, column 8: condition 'forecast CONTAINS \"sun\"' refers to undeclared data 'forecast'",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected issues:\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
+
+// TestValidate_NilRegistry tests that agent registration is not checked without a registry.
+func TestValidate_NilRegistry(t *testing.T) {
+	pr := parse(t, `TASK Rent AGENT CarRenter PARAMETERS () ;`)
+
+	if issues := validator.Validate(pr, nil); len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+}
+
+// TestValidate_ForEach tests that loop variables are in scope only inside their loop.
+func TestValidate_ForEach(t *testing.T) {
+	pr := parse(t, `START
+DATA destinations TYPE List VALUE "[\"NYC\", \"LAX\"]" ;
+DATA flights TYPE List ;
+DATA origin TYPE String ;
+PERM AGENT FlightGetter DATA origin ACCESS READ ;
+FOREACH destination IN destinations COLLECT flight INTO flights {
+    TASK SearchFlight AGENT FlightGetter PARAMETERS (origin=origin, destination=destination, OUTPUT=flight) ;
+    IF destination == "NYC" {
+        TASK Overwrite AGENT FlightGetter PARAMETERS (OUTPUT=destination) ;
+    }
+}
+TASK After AGENT FlightGetter PARAMETERS (destination=destination) ;
+FOREACH origin IN missing COLLECT x INTO nowhere {
+}
+UNTIL status == "done" MAX 3 {
+}
+END`)
+
+	var got []string
+	for _, issue := range validator.Validate(pr, agent.NewMockRegistry()) {
+		got = append(got, issue.Error())
+	}
+
+	expected := []string{
+		"line 9, column 9: task 'Overwrite' writes its output to 'destination', which is read-only in this scope",
+		"line 12, column 1: task 'After' parameter 'destination' refers to undeclared data 'destination'",
+		"line 13, column 1: FOREACH collects into undeclared data 'nowhere'",
+		"line 13, column 1: FOREACH loops over undeclared data 'missing'",
+		"line 13, column 1: FOREACH variable 'origin' shadows data with the same name",
+		"line 15, column 7: condition 'status == \"done\"' refers to undeclared data 'status'",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected issues:\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
+
+// TestValidate_TryCatch tests that the failed task and error message can be read only inside CATCH.
+func TestValidate_TryCatch(t *testing.T) {
+	pr := parse(t, `START
+DATA flightInfo TYPE String ;
+PERM AGENT FlightGetter DATA flightInfo ACCESS WRITE ;
+TRY {
+    TASK ScheduleFlight AGENT FlightGetter PARAMETERS (OUTPUT=flightInfo) ;
+} CATCH {
+    TASK Notify AGENT FlightGetter PARAMETERS (task=errorTask, message=errorMessage) ;
+    TASK Overwrite AGENT FlightGetter PARAMETERS (OUTPUT=errorMessage) ;
+} FINALLY {
+    TASK Report AGENT FlightGetter PARAMETERS (message=errorMessage) ;
+}
+END`)
+
+	var got []string
+	for _, issue := range validator.Validate(pr, agent.NewMockRegistry()) {
+		got = append(got, issue.Error())
+	}
+
+	expected := []string{
+		"line 8, column 5: task 'Overwrite' writes its output to 'errorMessage', which is read-only in this scope",
+		"line 10, column 5: task 'Report' parameter 'message' refers to undeclared data 'errorMessage'",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected issues:\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
+
+// TestValidate_Compensation tests that compensating tasks are checked like any other task.
+func TestValidate_Compensation(t *testing.T) {
+	pr := parse(t, `START
+DATA hotelInfo TYPE String ;
+PERM AGENT RoomBooker DATA hotelInfo ACCESS WRITE ;
+TASK BookHotel AGENT RoomBooker PARAMETERS (OUTPUT=hotelInfo)
+    COMPENSATE WITH TASK CancelHotel AGENT HotelDesk PARAMETERS (booking=hotelInfo) ;
+END`)
+
+	var got []string
+	for _, issue := range validator.Validate(pr, agent.NewMockRegistry()) {
+		got = append(got, issue.Error())
+	}
+
+	expected := []string{
+		"line 5, column 21: task 'CancelHotel' parameter 'booking' refers to 'hotelInfo', but agent 'HotelDesk' does not have READ permission for it",
+		"line 5, column 21: task 'CancelHotel' uses agent 'HotelDesk', which is not registered",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected issues:\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
+
+// TestValidate_Dependencies tests that dependency cycles and AFTER clauses outside RUNCON and AUTO are reported.
+func TestValidate_Dependencies(t *testing.T) {
+	pr := parse(t, `START
+RUNCON {
+    TASK A AGENT FlightGetter PARAMETERS () AFTER C ;
+    TASK B AGENT RoomBooker PARAMETERS () AFTER A ;
+    TASK C AGENT UberScheduler PARAMETERS () DEPENDS ON B ;
+    TASK D AGENT WeatherChecker PARAMETERS () AFTER A ;
+}
+RUNSEQ {
+    TASK E AGENT FlightGetter PARAMETERS () ;
+    TASK F AGENT RoomBooker PARAMETERS () AFTER E ;
+}
+AUTO {
+    TASK G AGENT FlightGetter PARAMETERS () AFTER H ;
+    TASK H AGENT RoomBooker PARAMETERS () AFTER G ;
+}
+END`)
+
+	var got []string
+	for _, issue := range validator.Validate(pr, agent.NewMockRegistry()) {
+		got = append(got, issue.Error())
+	}
+
+	expected := []string{
+		"line 2, column 1: RUNCON block has a dependency cycle: A -> C -> B -> A",
+		"line 10, column 5: task 'F' runs AFTER other statements, which only has an effect directly inside a RUNCON or AUTO block",
+		"line 12, column 1: AUTO block has a dependency cycle: G -> H -> G",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected issues:\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
+
+// TestValidate_Races tests that races between concurrent tasks are warnings, and errors in strict mode.
+func TestValidate_Races(t *testing.T) {
+	pr := parse(t, `START
+DATA hotelInfo TYPE String ;
+PERM AGENT RoomBooker DATA hotelInfo ACCESS READ, WRITE ;
+RUNCON {
+    TASK BookHotel AGENT RoomBooker PARAMETERS (OUTPUT=hotelInfo) ;
+    TASK Upgrade AGENT RoomBooker PARAMETERS (booking=hotelInfo, OUTPUT=hotelInfo) AFTER BookHotel ;
+    TASK Rebook AGENT RoomBooker PARAMETERS (OUTPUT=hotelInfo) ;
+}
+END`)
+
+	issues := validator.Validate(pr, agent.NewMockRegistry())
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.Error())
+	}
+	expected := []string{
+		"line 5, column 5: warning: write/write race on 'hotelInfo': task 'BookHotel' at 5:5 and task 'Rebook' at 7:5 both write it in the RUNCON block at 4:1",
+		"line 6, column 5: warning: read/write race on 'hotelInfo': task 'Rebook' at 7:5 writes it while task 'Upgrade' at 6:5 reads it in the RUNCON block at 4:1",
+		"line 6, column 5: warning: write/write race on 'hotelInfo': task 'Upgrade' at 6:5 and task 'Rebook' at 7:5 both write it in the RUNCON block at 4:1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected issues:\nExpected: %q\nGot:      %q", expected, got)
+	}
+	if validator.HasErrors(issues) {
+		t.Error("Expected races to be warnings by default")
+	}
+
+	strict := validator.ValidateWithOptions(pr, agent.NewMockRegistry(), validator.Options{Strict: true})
+	if len(strict) != len(expected) || !validator.HasErrors(strict) {
+		t.Errorf("Expected %d errors in strict mode, got %v", len(expected), strict)
+	}
+	for _, issue := range strict {
+		if issue.Severity != validator.SeverityError {
+			t.Errorf("Expected an error in strict mode, got %s: %v", issue.Severity, issue)
+		}
+	}
+}